@@ -45,15 +45,43 @@ var gitRev string
 var gitPull bool
 var products argList
 var resources argList
+var modules argList
 var output string
 var overrides string
 var templates string
 var dontGenerateCode bool
 var dontGenerateTests bool
+var generateUnitTests bool
 var overwrite bool
 var gitURL string
 var minVersion string
 var dontFormatFiles bool
+var maxRetries int
+var retryDelay float64
+var requestTimeout float64
+var argSpecSort string
+var retryMultiplier float64
+var recreateImmutable bool
+var requireOnPresent bool
+var defaultReturned string
+var compareDir string
+var parentContextLength int
+var maxParagraphs int
+var explainConflicts bool
+var versionAdded string
+var versionAddedCollection string
+var collection string
+var collectionVersion string
+var dontReturnInvocation bool
+var generateLookups bool
+var sensitivePatterns argList
+var validateScopes bool
+var generateGalaxy bool
+var generateRuntime bool
+var requiresAnsible string
+var generateModuleUtils bool
+var modulePath string
+var testPath string
 
 func init() {
 	flag.StringVar(&gitURL, "git-url", MMV1_REPO, "git repository to clone")
@@ -61,15 +89,43 @@ func init() {
 	flag.StringVar(&gitRev, "git-rev", "main", "git revision to checkout")
 	flag.BoolVar(&gitPull, "git-pull", false, "git pull before checkout")
 	flag.StringVar(&output, "output", "output", "path to write autogenerated files")
+	flag.StringVar(&modulePath, "module-path", tpl.DEFAULT_MODULE_PATH, "path (relative to -output) to write the modules to")
+	flag.StringVar(&testPath, "test-path", tpl.DEFAULT_TEST_PATH, "path (relative to -output) to write the integration test targets to")
 	flag.StringVar(&overrides, "overrides", "overrides", "path to override files")
 	flag.StringVar(&templates, "templates", "templates", "path to template files")
 	flag.Var(&products, "products", "comma-separated list of products to generate")
-	flag.Var(&resources, "resources", "comma-separated list of resources to generate")
+	flag.Var(&resources, "resources", "comma-separated list of resources (or globs) to generate")
+	flag.Var(&modules, "modules", "comma-separated list of module names (or globs e.g. gcp_alloydb_*) to generate")
 	flag.BoolVar(&dontGenerateCode, "no-code", false, "skip code generation")
 	flag.BoolVar(&dontGenerateTests, "no-tests", false, "skip test generation")
+	flag.BoolVar(&generateUnitTests, "unit-tests", false, "generate the argument spec unit tests (tests/unit/plugins/modules/test_<module>.py)")
 	flag.BoolVar(&dontFormatFiles, "no-format", false, "skip formatting files (i.e. black/yamlfmt)")
+	flag.BoolVar(&generateGalaxy, "galaxy", false, "generate the collection galaxy.yml (see -collection and -collection-version)")
+	flag.BoolVar(&generateRuntime, "runtime", false, "generate the collection meta/runtime.yml with the generated modules in the gcp action group")
+	flag.BoolVar(&generateModuleUtils, "module-utils", false, "generate the shared plugins/module_utils/gcp_utils.py (auth and request helpers)")
+	flag.StringVar(&requiresAnsible, "requires-ansible", ansible.DEFAULT_REQUIRES_ANSIBLE, "Ansible version constraint of the collection (meta/runtime.yml requires_ansible)")
+	flag.BoolVar(&generateLookups, "lookups", false, "generate a read-only lookup plugin per product")
 	flag.BoolVar(&overwrite, "overwrite", false, "overwrite existing files")
+	flag.BoolVar(&dontReturnInvocation, "no-invocation", false, "skip documenting the standard 'invocation' return value")
 	flag.StringVar(&minVersion, "min-version", MIN_VERSION, "minimum version to generate")
+	flag.StringVar(&compareDir, "compare-with", "", "path to an existing collection to check generated modules for breaking changes")
+	flag.IntVar(&maxRetries, "max-retries", ansible.DEFAULT_MAX_RETRIES, "maximum retries for transient API errors in generated modules")
+	flag.Float64Var(&retryDelay, "retry-delay", ansible.DEFAULT_RETRY_DELAY, "initial delay (in seconds) between retries in generated modules")
+	flag.Float64Var(&requestTimeout, "request-timeout", ansible.DEFAULT_REQUEST_TIMEOUT, "timeout (in seconds) of each API request in generated modules")
+	flag.StringVar(&argSpecSort, "argspec-sort", "", "order of the arguments (and nested options) in the generated argument specs (name-state-alpha, alpha or required-first), name-state-alpha with alphabetical nested options when not set")
+	flag.Float64Var(&retryMultiplier, "retry-multiplier", ansible.DEFAULT_RETRY_MULTIPLIER, "backoff multiplier between retries in generated modules (1 is constant)")
+	flag.BoolVar(&requireOnPresent, "require-on-present", false, "only require the required options outside the resource link with state=present")
+	flag.StringVar(&defaultReturned, "default-returned", ansible.DEFAULT_RETURNED, "RETURN 'returned' condition for optional fields (e.g. success)")
+	flag.IntVar(&parentContextLength, "parent-context-length", 0, "prefix nested option descriptions shorter than this with the parent option name (0 disables)")
+	flag.IntVar(&maxParagraphs, "max-paragraphs", 0, "truncate option descriptions longer than this many paragraphs (0 disables)")
+	flag.BoolVar(&explainConflicts, "explain-conflicts", false, "check mutually exclusive options in the generated modules with descriptive errors")
+	flag.StringVar(&versionAdded, "version-added", "", "collection version the generated modules were added in (version_added)")
+	flag.StringVar(&collection, "collection", ansible.DEFAULT_COLLECTION, "collection the modules are generated for, used in the User-Agent header")
+	flag.StringVar(&collectionVersion, "collection-version", "", "version of the collection, used in the User-Agent header")
+	flag.StringVar(&versionAddedCollection, "version-added-collection", "", "collection version_added refers to, for modules moved between collections")
+	flag.BoolVar(&validateScopes, "validate-scopes", false, "check the zone/region of the resource exists in the project before calling the API")
+	flag.Var(&sensitivePatterns, "sensitive-patterns", "comma-separated option name substrings that set no_log (defaults to password,secret,private_key,token)")
+	flag.BoolVar(&recreateImmutable, "recreate-immutable", false, "generate a force-guarded delete/create flow when immutable fields change")
 
 	// configure logging
 	logLevelStr := os.Getenv("LOG_LEVEL")
@@ -176,18 +232,37 @@ func doFindProducts(gitDir string, names []string, templateDir string, overrides
 }
 
 // doPopulateResourcesByProduct populates the resources for the given product
-func doPopulateResourcesByProduct(gitDir string, product *api.Product, names []string) error {
+// matchesAny returns true when the name matches any of the given glob patterns
+// (e.g. gcp_alloydb_* or an exact name)
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err != nil {
+			log.Warn().Msgf("invalid pattern %s: %v", pattern, err)
+		} else if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// doPopulateResourcesByProduct adds the resources of the product matching the
+// given names (or globs) and module names (or globs), all of them if empty
+func doPopulateResourcesByProduct(gitDir string, product *api.Product, names []string, moduleNames []string) error {
 	allFiles, _ := filepath.Glob(fmt.Sprintf("%s/mmv1/products/%s/*.yaml", gitDir, product.Name))
 	for _, rf := range allFiles {
 		if filepath.Base(rf) == "product.yaml" {
 			continue
 		}
 		rName := strings.TrimSuffix(filepath.Base(rf), filepath.Ext(filepath.Base(rf)))
-		if len(names) == 0 || slices.Contains(names, strings.ToLower(rName)) {
+		if len(names) == 0 || matchesAny(strings.ToLower(rName), names) {
 			r := api.NewResource(rf, product, product.TemplateDir, product.OverridesDir)
 			if r == nil {
 				continue
 			}
+			if len(moduleNames) > 0 && !matchesAny(r.AnsibleName(), moduleNames) {
+				log.Debug().Msgf("skipping resource %s, module %s not selected", rName, r.AnsibleName())
+				continue
+			}
 			product.Resources = append(product.Resources, r)
 		}
 	}
@@ -197,6 +272,9 @@ func doPopulateResourcesByProduct(gitDir string, product *api.Product, names []s
 
 func main() {
 	flag.Parse()
+	if argSpecSort != "" && !slices.Contains(ansible.SORT_MODES, ansible.SortMode(argSpecSort)) {
+		log.Fatal().Msgf("-argspec-sort must be one of %v, got %s", ansible.SORT_MODES, argSpecSort)
+	}
 	absDir, _ := filepath.Abs(gitDir)
 	templateDir, _ := filepath.Abs(templates)
 	overrideDir, _ := filepath.Abs(overrides)
@@ -212,15 +290,37 @@ func main() {
 
 	log.Info().Msgf("template directory is %v", templateDir)
 
-	templateData := tpl.NewTemplateData(templateDir, output, overwrite)
+	templateData := tpl.NewTemplateData(templateDir, output, modulePath, testPath, overwrite)
 	log.Debug().Msgf("template data: %v", templateData)
 
+	// generation settings shared by all modules
+	config := ansible.NewConfig()
+	config.Retry = ansible.NewRetryPolicy(maxRetries, retryDelay, retryMultiplier)
+	config.RequestTimeout = requestTimeout
+	config.ArgSpecSort = ansible.SortMode(argSpecSort)
+	config.RecreateImmutable = recreateImmutable
+	config.RequireOnPresent = requireOnPresent
+	config.DefaultReturned = defaultReturned
+	config.ParentContextLength = parentContextLength
+	config.MaxParagraphs = maxParagraphs
+	config.ExplainConflicts = explainConflicts
+	config.VersionAdded = versionAdded
+	config.VersionAddedCollection = versionAddedCollection
+	config.Collection = collection
+	config.CollectionVersion = collectionVersion
+	config.ReturnInvocation = !dontReturnInvocation
+	config.ValidateScopes = validateScopes
+	config.RequiresAnsible = requiresAnsible
+	if len(sensitivePatterns) > 0 {
+		config.SensitivePatterns = sensitivePatterns
+	}
+
 	// build list of modules to generate
 	modulesToGenerate := []*ansible.Module{}
 	minVersionObj := &mmv1product.Version{Name: minVersion}
 	for _, p := range productsToGenerate {
 		// populate resources for given products
-		err := doPopulateResourcesByProduct(gitDir, p, resources)
+		err := doPopulateResourcesByProduct(gitDir, p, resources, modules)
 		if err != nil {
 			log.Fatal().Err(err).Msg("failed to populate resources for product")
 		}
@@ -246,14 +346,72 @@ func main() {
 			}
 
 			// generate module struct
-			module := ansible.NewFromResource(r)
+			module := ansible.NewFromResource(r, config)
 			module.MinVersion = r.MinVersion()
+			for _, problem := range module.ValidateOptionShapes() {
+				log.Warn().Msgf("module %s: %s", module, problem)
+			}
+			if err := module.Validate(); err != nil {
+				log.Fatal().Err(err).Msgf("module %s can't be generated", module)
+			}
 			modulesToGenerate = append(modulesToGenerate, module)
 		}
 	}
+	// generate the collection metadata
+	if generateGalaxy {
+		collection, err := ansible.NewCollection(config)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to build collection metadata")
+		}
+		log.Info().Msgf("generating galaxy.yml for collection: %s", collection)
+		if err := templateData.GenerateGalaxy(collection); err != nil {
+			log.Fatal().Err(err).Msg("failed to generate galaxy.yml")
+		}
+	}
+
+	if generateRuntime {
+		log.Info().Msg("generating meta/runtime.yml")
+		if err := templateData.GenerateRuntime(ansible.NewRuntime(config, modulesToGenerate)); err != nil {
+			log.Fatal().Err(err).Msg("failed to generate meta/runtime.yml")
+		}
+	}
+
+	if generateModuleUtils {
+		log.Info().Msg("generating plugins/module_utils/gcp_utils.py")
+		if err := templateData.GenerateModuleUtils(ansible.NewModuleUtils(config)); err != nil {
+			log.Fatal().Err(err).Msg("failed to generate module_utils")
+		}
+	}
+
+	// generate lookup plugins
+	if generateLookups {
+		for _, p := range productsToGenerate {
+			lookup := ansible.NewLookupFromModules(p, modulesToGenerate, config)
+			if len(lookup.Resources) == 0 {
+				continue
+			}
+			log.Info().Msgf("generating lookup plugin: %s", lookup)
+			if err := templateData.GenerateLookup(lookup); err != nil {
+				log.Fatal().Err(err).Msg("failed to generate lookup plugin")
+			}
+
+			if !dontFormatFiles {
+				filePath := path.Join(templateData.LookupDirectory, fmt.Sprintf("%s.py", lookup))
+				if err := formatFile(filePath, "black"); err != nil {
+					log.Fatal().Err(err).Msg("failed to format lookup plugin")
+				}
+			}
+		}
+	}
+
 	// generate modules
 
 	for _, m := range modulesToGenerate {
+		// compare against the published module (if any) before overwriting it
+		if compareDir != "" {
+			compareWithExisting(m, compareDir)
+		}
+
 		// generate code for resources
 		if !dontGenerateCode {
 			log.Info().Msgf("generating code for ansible module: %s", m)
@@ -288,6 +446,45 @@ func main() {
 				}
 			}
 		}
+		// generate unit tests for resources
+		if generateUnitTests {
+			log.Info().Msgf("generating unit tests for ansible module: %s", m)
+			if err := templateData.GenerateUnitTests(m); err != nil {
+				log.Fatal().Err(err).Msg("failed to generate unit tests for ansible module")
+			}
+
+			if !dontFormatFiles {
+				filePath := path.Join(templateData.UnitTestDirectory, fmt.Sprintf("test_%s.py", m.Name))
+				log.Info().Msgf("formatting unit tests for %s", m.Name)
+				if err := formatFile(filePath, "black"); err != nil {
+					log.Fatal().Err(err).Msg("failed to format unit tests for ansible module")
+				}
+			}
+		}
+	}
+}
+
+// compareWithExisting logs the interface differences between the generated
+// module and the one published in the given collection directory
+func compareWithExisting(m *ansible.Module, collectionDir string) {
+	existingPath := path.Join(collectionDir, "plugins", "modules", fmt.Sprintf("%s.py", m.Name))
+	if _, err := os.Stat(existingPath); err != nil {
+		log.Info().Msgf("no published module found for %s, skipping comparison", m.Name)
+		return
+	}
+
+	diff, err := ansible.CompareWithExisting(m, existingPath)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to compare %s with the published module", m.Name)
+		return
+	}
+
+	if len(diff.AddedOptions) > 0 {
+		log.Info().Msgf("%s: new options %v", m.Name, diff.AddedOptions)
+	}
+	if diff.Breaking() {
+		log.Warn().Msgf("%s: breaking changes against %s: removed=%v newly-required=%v types=%v choices=%v",
+			m.Name, existingPath, diff.RemovedOptions, diff.NewlyRequired, diff.TypeChanges, diff.RemovedChoices)
 	}
 }
 
@@ -0,0 +1,67 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/thekad/magic-ansible/pkg/api"
+)
+
+func TestPopulateResourcesByProduct(t *testing.T) {
+	gitDir := t.TempDir()
+	productDir := filepath.Join(gitDir, "mmv1", "products", "widgets")
+	if err := os.MkdirAll(productDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"product.yaml": "name: Widgets\nversions:\n  - name: ga\n    base_url: https://widgets.googleapis.com/v1/\n",
+		"Widget.yaml":  "name: Widget\nbase_url: projects/{{project}}/widgets\n",
+		"Gadget.yaml":  "name: Gadget\nbase_url: projects/{{project}}/gadgets\n",
+		"Gizmo.yaml":   "name: Gizmo\nbase_url: projects/{{project}}/gizmos\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(productDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		resources []string
+		modules   []string
+		want      []string
+	}{
+		{"all", nil, nil, []string{"Gadget", "Gizmo", "Widget"}},
+		{"resource", []string{"widget"}, nil, []string{"Widget"}},
+		{"resource glob", []string{"g*"}, nil, []string{"Gadget", "Gizmo"}},
+		{"module", nil, []string{"gcp_widgets_gizmo"}, []string{"Gizmo"}},
+		{"module glob", nil, []string{"gcp_widgets_g*"}, []string{"Gadget", "Gizmo"}},
+		{"resource and module", []string{"g*"}, []string{"gcp_widgets_gadget"}, []string{"Gadget"}},
+		{"no match", []string{"nothing"}, nil, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := api.NewProduct(filepath.Join(productDir, "product.yaml"), t.TempDir(), t.TempDir())
+			if err := product.Unmarshal(); err != nil {
+				t.Fatal(err)
+			}
+			if err := doPopulateResourcesByProduct(gitDir, product, tt.resources, tt.modules); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range product.Resources {
+				got = append(got, r.Name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("resources = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@
 package ansible
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -17,6 +20,78 @@ type ArgumentSpec struct {
 
 	// Dependencies is the top-level dependency specification
 	Dependencies *Dependency
+
+	// SkipMutuallyExclusive leaves the top-level mutually_exclusive out, when
+	// the module checks it itself
+	SkipMutuallyExclusive bool
+
+	// SupportsCheckMode declares check mode support (supports_check_mode=True)
+	SupportsCheckMode bool
+
+	// SortMode is the order the arguments (and nested options) are written
+	// in. When empty the arguments are in the SortNameStateAlpha order and the
+	// nested options in the SortAlpha one
+	SortMode SortMode
+}
+
+// SortMode is the order of the arguments in the generated argument spec
+type SortMode string
+
+const (
+	// SortNameStateAlpha puts name first, then state, then the rest alphabetically
+	SortNameStateAlpha SortMode = "name-state-alpha"
+	// SortAlpha sorts all the arguments alphabetically
+	SortAlpha SortMode = "alpha"
+	// SortRequiredFirst puts the required arguments first, each group sorted
+	// alphabetically
+	SortRequiredFirst SortMode = "required-first"
+)
+
+// SORT_MODES are the supported argument spec sort modes
+var SORT_MODES = []SortMode{SortNameStateAlpha, SortAlpha, SortRequiredFirst}
+
+// sortedNames returns the names of the given (nested or not) options in the
+// SortMode order
+func (as *ArgumentSpec) sortedNames(options map[string]*Option, nested bool) []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+
+	mode := as.SortMode
+	if mode == "" && nested {
+		mode = SortAlpha
+	}
+
+	// rank returns the group of the option, lower groups come first
+	rank := func(name string) int {
+		switch mode {
+		case SortAlpha:
+			return 0
+		case SortRequiredFirst:
+			if options[name].Required {
+				return 0
+			}
+			return 1
+		default:
+			switch name {
+			case "name":
+				return 0
+			case "state":
+				return 1
+			}
+			return 2
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := rank(names[i]), rank(names[j])
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+
+	return names
 }
 
 // NewArgSpecFromOptions creates an ArgumentSpec from a map of Option structs
@@ -55,35 +130,7 @@ func (as *ArgumentSpec) ToString() string {
 	var builder strings.Builder
 	builder.WriteString("argument_spec=dict(\n")
 
-	// Sort argument names with priority ordering for readability
-	argNames := make([]string, 0, len(as.Arguments))
-	for name := range as.Arguments {
-		argNames = append(argNames, name)
-	}
-
-	// Custom sort: name first, then state, then alphabetical
-	sort.Slice(argNames, func(i, j int) bool {
-		a, b := argNames[i], argNames[j]
-
-		// name always comes first
-		if a == "name" {
-			return true
-		}
-		if b == "name" {
-			return false
-		}
-
-		// state comes second (after name)
-		if a == "state" {
-			return true
-		}
-		if b == "state" {
-			return false
-		}
-
-		// Everything else in alphabetical order
-		return a < b
-	})
+	argNames := as.sortedNames(as.Arguments, false)
 
 	// Generate argument specifications
 	for i, argName := range argNames {
@@ -98,13 +145,18 @@ func (as *ArgumentSpec) ToString() string {
 			builder.WriteString("        required=True,\n")
 		}
 
+		// Add aliases
+		if len(option.Aliases) > 0 {
+			builder.WriteString(fmt.Sprintf("        aliases=%s,\n", pythonList(option.Aliases)))
+		}
+
 		// Add default
 		if option.Default != nil {
 			builder.WriteString(fmt.Sprintf("        default=%s,\n", pythonValue(option.Default)))
 		}
 
 		// Add choices
-		if len(option.Choices) > 0 {
+		if len(option.Choices) > 0 && option.StrictChoices() {
 			builder.WriteString(fmt.Sprintf("        choices=%s,\n", pythonList(option.Choices)))
 		}
 
@@ -116,6 +168,13 @@ func (as *ArgumentSpec) ToString() string {
 		// Add no_log
 		if option.NoLog {
 			builder.WriteString("        no_log=True,\n")
+		} else if option.NotSensitive() {
+			builder.WriteString("        no_log=False,\n")
+		}
+
+		// Add fallback
+		if option.Fallback != nil {
+			builder.WriteString(fmt.Sprintf("        fallback=%s,\n", pythonFallback(option.Fallback)))
 		}
 
 		// Add nested options
@@ -149,14 +208,106 @@ func (as *ArgumentSpec) ToString() string {
 	return builder.String()
 }
 
+// ToJSON returns the argument spec as a JSON object shaped like the keyword
+// arguments of AnsibleModule: argument_spec (nested options included) and the
+// module-level constraints
+func (as *ArgumentSpec) ToJSON() (string, error) {
+	spec := map[string]interface{}{
+		"argument_spec": jsonArguments(as.Arguments, false),
+	}
+	if as.SupportsCheckMode {
+		spec["supports_check_mode"] = true
+	}
+	if as.Dependencies != nil {
+		dependency := *as.Dependencies
+		if as.SkipMutuallyExclusive {
+			dependency.MutuallyExclusive = nil
+		}
+		for key, value := range jsonConstraints(&dependency) {
+			spec[key] = value
+		}
+	}
+
+	out, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("cannot serialize the argument spec: %v", err)
+	}
+	return string(out), nil
+}
+
+// jsonArguments converts the given options to their argument spec entries,
+// the same way ToString does (nested options are only required without a
+// default)
+func jsonArguments(options map[string]*Option, nested bool) map[string]interface{} {
+	arguments := map[string]interface{}{}
+	for name, option := range options {
+		argument := map[string]interface{}{}
+		if option.Type != "" {
+			argument["type"] = option.Type.String()
+		}
+		if option.Required && (!nested || option.Default == nil) {
+			argument["required"] = true
+		}
+		if len(option.Aliases) > 0 {
+			argument["aliases"] = option.Aliases
+		}
+		if option.Default != nil {
+			argument["default"] = option.Default
+		}
+		if len(option.Choices) > 0 && option.StrictChoices() {
+			argument["choices"] = option.Choices
+		}
+		if option.Elements != "" {
+			argument["elements"] = option.Elements.String()
+		}
+		if option.NoLog {
+			argument["no_log"] = true
+		} else if option.NotSensitive() {
+			argument["no_log"] = false
+		}
+		if option.Fallback != nil {
+			argument["fallback"] = []interface{}{"env_fallback", option.Fallback.EnvVars}
+		}
+		if len(option.Suboptions) > 0 {
+			argument["options"] = jsonArguments(option.Suboptions, true)
+		}
+		for key, value := range jsonConstraints(option.Dependency) {
+			argument[key] = value
+		}
+		arguments[name] = argument
+	}
+	return arguments
+}
+
+// jsonConstraints converts the given dependency to the argument spec
+// constraint keys, required_if entries become [key, value, requirements]
+func jsonConstraints(dependency *Dependency) map[string]interface{} {
+	constraints := map[string]interface{}{}
+	if dependency == nil {
+		return constraints
+	}
+	if len(dependency.MutuallyExclusive) > 0 {
+		constraints["mutually_exclusive"] = dependency.MutuallyExclusive
+	}
+	if len(dependency.RequiredTogether) > 0 {
+		constraints["required_together"] = dependency.RequiredTogether
+	}
+	if len(dependency.RequiredOneOf) > 0 {
+		constraints["required_one_of"] = dependency.RequiredOneOf
+	}
+	if len(dependency.RequiredIf) > 0 {
+		requiredIf := make([][]interface{}, 0, len(dependency.RequiredIf))
+		for _, ri := range dependency.RequiredIf {
+			requiredIf = append(requiredIf, []interface{}{ri.Key, ri.Value, ri.Requirements})
+		}
+		constraints["required_if"] = requiredIf
+	}
+	return constraints
+}
+
 // writeNestedOptions recursively writes nested argument options using dict() constructor
 func (as *ArgumentSpec) writeNestedOptions(builder *strings.Builder, options map[string]*Option, indent string) {
-	// Sort option names for consistent output
-	optionNames := make([]string, 0, len(options))
-	for name := range options {
-		optionNames = append(optionNames, name)
-	}
-	sort.Strings(optionNames)
+	optionNames := as.sortedNames(options, true)
 
 	for i, optionName := range optionNames {
 		option := options[optionName]
@@ -172,13 +323,18 @@ func (as *ArgumentSpec) writeNestedOptions(builder *strings.Builder, options map
 			builder.WriteString(fmt.Sprintf("%s    required=True,\n", indent))
 		}
 
+		// Add aliases
+		if len(option.Aliases) > 0 {
+			builder.WriteString(fmt.Sprintf("%s    aliases=%s,\n", indent, pythonList(option.Aliases)))
+		}
+
 		// Add default
 		if option.Default != nil {
 			builder.WriteString(fmt.Sprintf("%s    default=%s,\n", indent, pythonValue(option.Default)))
 		}
 
 		// Add choices
-		if len(option.Choices) > 0 {
+		if len(option.Choices) > 0 && option.StrictChoices() {
 			builder.WriteString(fmt.Sprintf("%s    choices=%s,\n", indent, pythonList(option.Choices)))
 		}
 
@@ -190,6 +346,13 @@ func (as *ArgumentSpec) writeNestedOptions(builder *strings.Builder, options map
 		// Add no_log
 		if option.NoLog {
 			builder.WriteString(fmt.Sprintf("%s    no_log=True,\n", indent))
+		} else if option.NotSensitive() {
+			builder.WriteString(fmt.Sprintf("%s    no_log=False,\n", indent))
+		}
+
+		// Add fallback
+		if option.Fallback != nil {
+			builder.WriteString(fmt.Sprintf("%s    fallback=%s,\n", indent, pythonFallback(option.Fallback)))
 		}
 
 		// Add nested options recursively
@@ -221,6 +384,12 @@ func (as *ArgumentSpec) writeArgumentConstraints(builder *strings.Builder, optio
 		if len(option.Dependency.RequiredTogether) > 0 {
 			builder.WriteString(fmt.Sprintf("%srequired_together=%s,\n", indent, pythonListOfLists(option.Dependency.RequiredTogether)))
 		}
+		if len(option.Dependency.RequiredOneOf) > 0 {
+			builder.WriteString(fmt.Sprintf("%srequired_one_of=%s,\n", indent, pythonListOfLists(option.Dependency.RequiredOneOf)))
+		}
+		if len(option.Dependency.RequiredIf) > 0 {
+			builder.WriteString(fmt.Sprintf("%srequired_if=%s,\n", indent, pythonRequiredIf(option.Dependency.RequiredIf)))
+		}
 	}
 }
 
@@ -228,15 +397,24 @@ func (as *ArgumentSpec) writeArgumentConstraints(builder *strings.Builder, optio
 func (as *ArgumentSpec) buildModuleConstraints() string {
 	var constraints []string
 
+	if as.SupportsCheckMode {
+		constraints = append(constraints, "supports_check_mode=True")
+	}
 	if as.Dependencies == nil {
-		return ""
+		return strings.Join(constraints, ",\n")
 	}
-	if len(as.Dependencies.MutuallyExclusive) > 0 {
+	if len(as.Dependencies.MutuallyExclusive) > 0 && !as.SkipMutuallyExclusive {
 		constraints = append(constraints, fmt.Sprintf("mutually_exclusive=%s", pythonListOfLists(as.Dependencies.MutuallyExclusive)))
 	}
 	if len(as.Dependencies.RequiredTogether) > 0 {
 		constraints = append(constraints, fmt.Sprintf("required_together=%s", pythonListOfLists(as.Dependencies.RequiredTogether)))
 	}
+	if len(as.Dependencies.RequiredOneOf) > 0 {
+		constraints = append(constraints, fmt.Sprintf("required_one_of=%s", pythonListOfLists(as.Dependencies.RequiredOneOf)))
+	}
+	if len(as.Dependencies.RequiredIf) > 0 {
+		constraints = append(constraints, fmt.Sprintf("required_if=%s", pythonRequiredIf(as.Dependencies.RequiredIf)))
+	}
 	if len(constraints) == 0 {
 		return ""
 	}
@@ -246,6 +424,31 @@ func (as *ArgumentSpec) buildModuleConstraints() string {
 
 // Python formatting helper functions
 
+// PYTHON_KEYWORDS can't be used as identifiers in the generated code
+var PYTHON_KEYWORDS = []string{
+	"False", "None", "True", "and", "as", "assert", "break", "class", "continue",
+	"def", "del", "elif", "else", "except", "finally", "for", "from", "global",
+	"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass",
+	"raise", "return", "try", "while", "with", "yield",
+}
+
+// PYTHON_BUILTINS are valid identifiers that would shadow a builtin when used
+// as a local variable in the generated code
+var PYTHON_BUILTINS = []string{
+	"all", "any", "bytes", "dict", "dir", "filter", "format", "hash", "id",
+	"input", "iter", "len", "list", "map", "max", "min", "next", "object",
+	"open", "range", "set", "sorted", "str", "sum", "type", "vars", "zip",
+}
+
+// pythonVarName returns a local variable name for the given name that doesn't
+// collide with a python keyword or builtin, by suffixing it with an underscore
+func pythonVarName(s string) string {
+	if slices.Contains(PYTHON_KEYWORDS, s) || slices.Contains(PYTHON_BUILTINS, s) {
+		return s + "_"
+	}
+	return s
+}
+
 // pythonIdentifier formats a string as a Python identifier for use in dict() constructor
 // If the string is not a valid Python identifier, it falls back to quoted string
 func pythonIdentifier(s string) string {
@@ -270,28 +473,26 @@ func pythonIdentifier(s string) string {
 		}
 	}
 
-	// Check if it's a Python keyword (basic list)
-	keywords := []string{
-		"False", "None", "True", "and", "as", "assert", "break", "class", "continue",
-		"def", "del", "elif", "else", "except", "finally", "for", "from", "global",
-		"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass",
-		"raise", "return", "try", "while", "with", "yield",
-	}
-
-	for _, keyword := range keywords {
-		if s == keyword {
-			return pythonQuote(s)
-		}
+	// Check if it's a Python keyword
+	if slices.Contains(PYTHON_KEYWORDS, s) {
+		return pythonQuote(s)
 	}
 
 	return s
 }
 
-// pythonQuote adds single quotes around a string for Python
+// PYTHON_STRING_ESCAPER escapes the characters that would end or break a
+// double-quoted python string literal, backslashes first
+var PYTHON_STRING_ESCAPER = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "\\\"",
+	"\n", "\\n",
+	"\r", "\\r",
+)
+
+// pythonQuote adds double quotes around a string for Python
 func pythonQuote(s string) string {
-	// Escape single quotes in the string
-	escaped := strings.ReplaceAll(s, "\"", "\\\"")
-	return fmt.Sprintf("\"%s\"", escaped)
+	return fmt.Sprintf("\"%s\"", PYTHON_STRING_ESCAPER.Replace(s))
 }
 
 // pythonValue converts a Go value to its Python representation
@@ -312,8 +513,27 @@ func pythonValue(value interface{}) string {
 		return fmt.Sprintf("%v", v)
 	case float32, float64:
 		return fmt.Sprintf("%v", v)
+	}
+
+	// lists and dicts (e.g. []interface{} or map[string]interface{} defaults)
+	// are rendered recursively, dict keys sorted for a stable output
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, pythonValue(rv.Index(i).Interface()))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	case reflect.Map:
+		items := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			items = append(items, fmt.Sprintf("%s: %s", pythonValue(key.Interface()), pythonValue(rv.MapIndex(key).Interface())))
+		}
+		sort.Strings(items)
+		return fmt.Sprintf("{%s}", strings.Join(items, ", "))
 	default:
-		return pythonQuote(fmt.Sprintf("%v", v))
+		return pythonQuote(fmt.Sprintf("%v", value))
 	}
 }
 
@@ -330,6 +550,12 @@ func pythonList(items []string) string {
 	return fmt.Sprintf("[%s]", strings.Join(quoted, ", "))
 }
 
+// pythonFallback converts a Fallback to the (env_fallback, [...]) tuple
+// expected by the argument spec
+func pythonFallback(fallback *Fallback) string {
+	return fmt.Sprintf("(env_fallback, %s)", pythonList(fallback.EnvVars))
+}
+
 // pythonListOfLists converts a slice of string slices to a Python list of lists
 func pythonListOfLists(items [][]string) string {
 	if len(items) == 0 {
@@ -342,3 +568,13 @@ func pythonListOfLists(items [][]string) string {
 	}
 	return fmt.Sprintf("[%s]", strings.Join(lists, ", "))
 }
+
+// pythonRequiredIf converts a list of RequiredIf to the python list of
+// [key, value, requirements] lists expected by the argument spec
+func pythonRequiredIf(items []*RequiredIf) string {
+	var lists []string
+	for _, ri := range items {
+		lists = append(lists, fmt.Sprintf("[%s, %s, %s]", pythonQuote(ri.Key), pythonValue(ri.Value), pythonList(ri.Requirements)))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(lists, ", "))
+}
@@ -0,0 +1,307 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPythonValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"nil", nil, "None"},
+		{"bool", true, "True"},
+		{"int", 42, "42"},
+		{"string list", []string{"a", "b"}, `["a", "b"]`},
+		{"interface list", []interface{}{"a", 1, false}, `["a", 1, False]`},
+		{
+			name:  "dict",
+			value: map[string]interface{}{"zone": "us-central1-a", "enabled": true, "count": 3},
+			want:  `{"count": 3, "enabled": True, "zone": "us-central1-a"}`,
+		},
+		{
+			name:  "nested dict",
+			value: map[string]interface{}{"b": []interface{}{"x"}, "a": map[string]interface{}{"d": nil, "c": 1.5}},
+			want:  `{"a": {"c": 1.5, "d": None}, "b": ["x"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// maps are iterated in random order, the keys must still be sorted
+			for i := 0; i < 10; i++ {
+				if got := pythonValue(tt.value); got != tt.want {
+					t.Fatalf("pythonValue(%v) = %s, want %s", tt.value, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPythonQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "us-central1", `"us-central1"`},
+		{"backslashes", `path\to\file`, `"path\\to\\file"`},
+		{"newline", "first\nsecond", `"first\nsecond"`},
+		{"escaped quote", `say \"hi\"`, `"say \\\"hi\\\""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pythonQuote(tt.value); got != tt.want {
+				t.Errorf("pythonQuote(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// argumentBlock returns the dict(...) of the named top-level argument in the
+// given argument spec, empty if it isn't there
+func argumentBlock(spec, name string) string {
+	start := strings.Index(spec, "\n    "+name+"=dict(\n")
+	if start < 0 {
+		return ""
+	}
+	end := strings.Index(spec[start+1:], "\n    ),")
+	if end < 0 {
+		return spec[start+1:]
+	}
+	return spec[start+1 : start+1+end]
+}
+
+func TestNotSensitive(t *testing.T) {
+	resource := testResourceYAML + `  - name: apiKey
+    type: String
+    description: The API key identifier.
+    no_log: false
+  - name: keyRing
+    type: String
+    description: The key ring.
+  - name: serviceAccount
+    type: String
+    description: The service account.
+  - name: kmsKeyName
+    type: String
+    description: The KMS key.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+	spec := m.ArgumentSpec.ToString()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"service_account_email", "no_log=False"},
+		{"kms_key_name", "no_log=False"},
+		{"api_key", "no_log=False"},
+		{"key_ring", ""},
+		{"service_account", ""},
+		{"display_name", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := argumentBlock(spec, tt.name)
+			if block == "" {
+				t.Fatalf("%s is not in the argument spec:\n%s", tt.name, spec)
+			}
+			if tt.want == "" && strings.Contains(block, "no_log") {
+				t.Errorf("%s sets no_log:\n%s", tt.name, block)
+			}
+			if tt.want != "" && !strings.Contains(block, tt.want) {
+				t.Errorf("%s doesn't set %s:\n%s", tt.name, tt.want, block)
+			}
+		})
+	}
+}
+
+func TestSortMode(t *testing.T) {
+	resource := testResourceYAML + `  - name: config
+    type: NestedObject
+    description: The configuration.
+    properties:
+      - name: zone
+        type: String
+        description: The zone.
+      - name: autoScale
+        type: Boolean
+        description: Whether the widget scales.
+      - name: name
+        type: String
+        description: The configuration name.
+      - name: tier
+        type: String
+        description: The tier.
+        required: true
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+	options := map[string]*Option{}
+	for _, name := range []string{"name", "state", "config", "display_name", "size"} {
+		options[name] = m.Options[name]
+	}
+
+	for _, mode := range append([]SortMode{""}, SORT_MODES...) {
+		name := string(mode)
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			spec := NewArgSpecFromOptions(options, nil)
+			spec.SortMode = mode
+			golden := filepath.Join("testdata", "argspec", name+".py")
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.ToString() + "\n"; got != string(want) {
+				t.Errorf("%s argument spec = \n%s\nwant (%s)\n%s", name, got, golden, want)
+			}
+		})
+	}
+}
+
+func TestPythonVarName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"type", "type_"},
+		{"id", "id_"},
+		{"lambda", "lambda_"},
+		{"display_name", "display_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pythonVarName(tt.name); got != tt.want {
+				t.Errorf("pythonVarName(%s) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListElementConstraints(t *testing.T) {
+	resource := testResourceYAML + `  - name: rules
+    type: Array
+    description: The widget rules.
+    item_type:
+      type: NestedObject
+      properties:
+        - name: allow
+          type: String
+          description: The allowed source.
+          conflicts:
+            - rules.0.deny
+        - name: deny
+          type: String
+          description: The denied source.
+          conflicts:
+            - rules.0.allow
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	spec := m.ArgumentSpec.ToString()
+	block := argumentBlock(spec, "rules")
+	want := `mutually_exclusive=[["allow", "deny"]],`
+	if !strings.Contains(block, want) {
+		t.Errorf("want %s in the rules argument:\n%s", want, block)
+	}
+	if strings.Count(spec, `["allow", "deny"]`) != 1 {
+		t.Errorf("want the constraint on the rules elements only:\n%s", spec)
+	}
+}
+
+func TestStrictChoices(t *testing.T) {
+	resource := testResourceYAML + `  - name: tier
+    type: Enum
+    description: The widget tier.
+    enum_values:
+      - BASIC
+      - PREMIUM
+  - name: zone
+    type: Enum
+    description: The widget zone.
+    enum_values:
+      - NORTH
+      - SOUTH
+    strict_choices: false
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+	spec := m.ArgumentSpec.ToString()
+	doc := m.Documentation.ToString()
+
+	tests := []struct {
+		name    string
+		choices string
+		strict  bool
+	}{
+		{"tier", `choices=["BASIC", "PREMIUM"],`, true},
+		{"zone", `choices=["NORTH", "SOUTH"],`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Options[tt.name].StrictChoices(); got != tt.strict {
+				t.Errorf("StrictChoices() = %v, want %v", got, tt.strict)
+			}
+			if block := argumentBlock(spec, tt.name); strings.Contains(block, tt.choices) != tt.strict {
+				t.Errorf("want %s in the argument %v:\n%s", tt.choices, tt.strict, block)
+			}
+			if block := documentationBlock(doc, tt.name); !strings.Contains(block, "\n    choices:\n") {
+				t.Errorf("the choices aren't documented:\n%s", block)
+			}
+		})
+	}
+}
+
+func TestResourceRefRequiredTogether(t *testing.T) {
+	tests := []struct {
+		name         string
+		selfLink     string
+		wantTogether string
+	}{
+		{"components", "projects/{{project}}/locations/{{location}}/gadgets/{{gadget_id}}", `required_together=[["project", "location", "gadget_id"]],`},
+		{"single component", "gadgets/{{gadget_id}}", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gadget := strings.Replace(testGadgetYAML, "projects/{{project}}/locations/{{location}}/gadgets/{{gadget_id}}", tt.selfLink, 1)
+			resource := testResourceYAML + `  - name: gadget
+    type: ResourceRef
+    description: The gadget of the widget.
+    resource: Gadget
+    imports: name
+`
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource, "Gadget": gadget}, "Widget", nil)
+			block := argumentBlock(m.ArgumentSpec.ToString(), "gadget")
+			if block == "" {
+				t.Fatalf("no gadget argument")
+			}
+			if !strings.Contains(block, `required_one_of=[["self_link", "gadget_id"]],`) {
+				t.Errorf("gadget isn't required by self_link or its name:\n%s", block)
+			}
+			if tt.wantTogether == "" {
+				if strings.Contains(block, "required_together=") {
+					t.Errorf("a single component is required together:\n%s", block)
+				}
+				return
+			}
+			if !strings.Contains(block, tt.wantTogether) {
+				t.Errorf("want %s in:\n%s", tt.wantTogether, block)
+			}
+			if description := m.Options["gadget"].Suboptions["project"].Description; strings.Contains(strings.Join(description, " "), "defaults to the module") {
+				t.Errorf("project component description = %q, it no longer defaults to the module option", description)
+			}
+		})
+	}
+}
@@ -9,23 +9,78 @@ import (
 	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
 )
 
+const (
+	// ASYNC_OPERATION is the MMv1 async type of the APIs returning a long
+	// running operation to poll
+	ASYNC_OPERATION = "OpAsync"
+
+	// ASYNC_POLL is the MMv1 async type of the APIs returning right away, the
+	// resource itself is polled until it exists (or is gone)
+	ASYNC_POLL = "PollAsync"
+)
+
 type AsyncOps struct {
+	Type         string         `json:"type"`
 	LinkTemplate string         `json:"base_url"`
 	Timeouts     map[string]int `json:"timeouts"`
 	Actions      []string       `json:"actions"`
+
+	// StatusPath is the (dot-separated) path of the operation status, the
+	// operation is done when it is DONE. Empty when DonePath is used instead
+	StatusPath string `json:"status_path"`
+
+	// DonePath is the path of the operation boolean done flag
+	DonePath string `json:"done_path"`
+
+	// ErrorPath is the path of the operation error, set when it failed
+	ErrorPath string `json:"error_path"`
+
+	// TargetLinkPath is the path of the link of the resource the operation
+	// is about
+	TargetLinkPath string `json:"target_link_path"`
 }
 
-// NewAsyncOps creates a custom AsyncOps struct from a link template and timeouts
-// which is easier to just serialize to JSON in the template
-func NewAsyncOps(linkTemplate string, actions []string, timeouts *mmv1api.Timeouts) *AsyncOps {
+// NewAsyncOps creates a custom AsyncOps struct out of the MMv1 async of the
+// resource, which is easier to just serialize to JSON in the template. The
+// fields are empty when the resource isn't async.
+//
+// MMv1 doesn't describe the operation itself, the paths follow the operation
+// shape of the API: compute operations have a status and a targetLink, the
+// others are google.longrunning operations with a done flag
+func NewAsyncOps(resource *mmv1api.Resource) *AsyncOps {
 	r := &AsyncOps{
-		LinkTemplate: strings.ReplaceAll(strings.ReplaceAll(linkTemplate, "{{", "{"), "}}", "}"),
-		Actions:      actions,
-		Timeouts: map[string]int{
-			"create": timeouts.InsertMinutes * 60,
-			"delete": timeouts.DeleteMinutes * 60,
-			"update": timeouts.UpdateMinutes * 60,
-		},
+		Timeouts: map[string]int{},
+		Actions:  []string{},
+	}
+	async := resource.GetAsync()
+	if async == nil {
+		return r
+	}
+
+	timeouts := resource.GetTimeouts()
+	if async.Operation != nil && async.Operation.Timeouts != nil {
+		timeouts = async.Operation.Timeouts
+	}
+	r.Type = async.Type
+	r.Actions = async.Actions
+	r.Timeouts = map[string]int{
+		"create": timeoutMinutes(timeouts.InsertMinutes) * 60,
+		"delete": timeoutMinutes(timeouts.DeleteMinutes) * 60,
+		"update": timeoutMinutes(timeouts.UpdateMinutes) * 60,
+	}
+
+	if !async.IsA(ASYNC_OPERATION) || async.Operation == nil {
+		return r
+	}
+	r.LinkTemplate = strings.ReplaceAll(strings.ReplaceAll(async.Operation.BaseUrl, "{{", "{"), "}}", "}")
+	if resource.ProductMetadata != nil && resource.ProductMetadata.Name == "Compute" {
+		r.StatusPath = "status"
+		r.ErrorPath = "error.errors"
+		r.TargetLinkPath = "targetLink"
+	} else {
+		r.DonePath = "done"
+		r.ErrorPath = "error"
+		r.TargetLinkPath = "metadata.target"
 	}
 
 	return r
@@ -0,0 +1,81 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import "slices"
+
+// AUTH_KINDS are the authentication methods supported by the google.cloud collection
+var AUTH_KINDS = []string{"application", "machineaccount", "serviceaccount", "accesstoken"}
+
+// CREDENTIAL_OPTIONS are the auth options carrying secrets (or where to read
+// them from), these are always no_log
+var CREDENTIAL_OPTIONS = []string{"service_account_contents", "service_account_file", "access_token"}
+
+// maskCredentialOptions sets no_log on every credential option in the given
+// map, whether it was injected or comes from the resource
+func maskCredentialOptions(options map[string]*Option) {
+	for name, option := range options {
+		if slices.Contains(CREDENTIAL_OPTIONS, name) {
+			option.NoLog = true
+		}
+	}
+}
+
+// newAuthOptions returns the standard GCP authentication options that every
+// generated module accepts. These are documented by the google.cloud.gcp doc
+// fragment, so they are only added to the argument spec
+func newAuthOptions() map[string]*Option {
+	return map[string]*Option{
+		"project": {
+			Name:        "project",
+			Description: []string{"The Google Cloud Platform project to use."},
+			Type:        TypeStr,
+			Fallback:    NewEnvFallback("GCP_PROJECT", "CLOUDSDK_CORE_PROJECT"),
+		},
+		"auth_kind": {
+			Name:        "auth_kind",
+			Description: []string{"The type of credential used."},
+			Type:        TypeStr,
+			Required:    true,
+			Choices:     AUTH_KINDS,
+			Fallback:    NewEnvFallback("GCP_AUTH_KIND"),
+		},
+		"service_account_email": {
+			Name:        "service_account_email",
+			Description: []string{"An optional service account email address if machineaccount is selected and the user does not wish to use the default email."},
+			Type:        TypeStr,
+			Fallback:    NewEnvFallback("GCP_SERVICE_ACCOUNT_EMAIL"),
+		},
+		"service_account_file": {
+			Name:        "service_account_file",
+			Description: []string{"The path of a Service Account JSON file if serviceaccount is selected as type."},
+			Type:        TypePath,
+			Fallback:    NewEnvFallback("GCP_SERVICE_ACCOUNT_FILE"),
+		},
+		"service_account_contents": {
+			Name:        "service_account_contents",
+			Description: []string{"The contents of a Service Account JSON file, either in a dictionary or as a JSON string that represents it."},
+			Type:        TypeJsonarg,
+			Fallback:    NewEnvFallback("GCP_SERVICE_ACCOUNT_CONTENTS"),
+		},
+		"access_token": {
+			Name:        "access_token",
+			Description: []string{"An OAuth2 access token if credential type is accesstoken."},
+			Type:        TypeStr,
+			Fallback:    NewEnvFallback("GCP_ACCESS_TOKEN"),
+		},
+		"scopes": {
+			Name:        "scopes",
+			Description: []string{"Array of scopes to be used."},
+			Type:        TypeList,
+			Elements:    TypeStr,
+			Fallback:    NewEnvFallback("GCP_SCOPES"),
+		},
+		"env_type": {
+			Name:        "env_type",
+			Description: []string{"Specifies which Ansible environment you're running this module within."},
+			Type:        TypeStr,
+		},
+	}
+}
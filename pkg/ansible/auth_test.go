@@ -0,0 +1,69 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCredentialOptions(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	spec := m.ArgumentSpec.ToString()
+
+	for name, want := range map[string]bool{
+		"service_account_contents": true,
+		"service_account_file":     true,
+		"access_token":             true,
+		"project":                  false,
+		"scopes":                   false,
+		"auth_kind":                false,
+		"service_account_email":    false,
+	} {
+		block := argumentBlock(spec, name)
+		if block == "" {
+			t.Errorf("no %s argument in the argument spec", name)
+			continue
+		}
+		if got := strings.Contains(block, "no_log=True"); got != want {
+			t.Errorf("%s no_log=True is %v, want %v:\n%s", name, got, want, block)
+		}
+	}
+}
+
+func TestAuthEnvFallback(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	spec := m.ArgumentSpec.ToString()
+
+	for name, want := range map[string]string{
+		"auth_kind":                `fallback=(env_fallback, ["GCP_AUTH_KIND"]),`,
+		"service_account_email":    `fallback=(env_fallback, ["GCP_SERVICE_ACCOUNT_EMAIL"]),`,
+		"service_account_file":     `fallback=(env_fallback, ["GCP_SERVICE_ACCOUNT_FILE"]),`,
+		"service_account_contents": `fallback=(env_fallback, ["GCP_SERVICE_ACCOUNT_CONTENTS"]),`,
+		"access_token":             `fallback=(env_fallback, ["GCP_ACCESS_TOKEN"]),`,
+		"scopes":                   `fallback=(env_fallback, ["GCP_SCOPES"]),`,
+	} {
+		if block := argumentBlock(spec, name); !strings.Contains(block, want) {
+			t.Errorf("want %s in the %s argument:\n%s", want, name, block)
+		}
+	}
+	if block := argumentBlock(spec, "env_type"); strings.Contains(block, "fallback=") {
+		t.Errorf("env_type has a fallback:\n%s", block)
+	}
+}
+
+func TestProjectEnvFallback(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+
+	// the resource project parameter replaces the injected one, it keeps the fallback
+	project := m.ArgumentSpec.Arguments["project"]
+	if project == nil || project.Fallback == nil || !slices.Equal(project.Fallback.EnvVars, []string{"GCP_PROJECT", "CLOUDSDK_CORE_PROJECT"}) {
+		t.Fatalf("project fallback = %+v, want GCP_PROJECT and CLOUDSDK_CORE_PROJECT", project)
+	}
+	want := `fallback=(env_fallback, ["GCP_PROJECT", "CLOUDSDK_CORE_PROJECT"]),`
+	if block := argumentBlock(m.ArgumentSpec.ToString(), "project"); !strings.Contains(block, want) {
+		t.Errorf("want %s in the project argument:\n%s", want, block)
+	}
+}
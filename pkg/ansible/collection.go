@@ -0,0 +1,122 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// DEFAULT_COLLECTION_VERSION is the galaxy.yml version when none is configured
+	DEFAULT_COLLECTION_VERSION = "0.0.1"
+
+	// DEFAULT_REQUIRES_ANSIBLE is the meta/runtime.yml requires_ansible when
+	// none is configured
+	DEFAULT_REQUIRES_ANSIBLE = ">=2.14"
+)
+
+// Collection holds the metadata of the collection the modules are published in
+type Collection struct {
+	Namespace    string
+	Name         string
+	Version      string
+	Dependencies map[string]string
+}
+
+// NewCollection is a constructor that returns the Collection metadata out of
+// the generation config, collections other than google.cloud depend on it
+// since the generated modules use its module_utils
+func NewCollection(config *Config) (*Collection, error) {
+	fqcn := config.Collection
+	if fqcn == "" {
+		fqcn = DEFAULT_COLLECTION
+	}
+	namespace, name, ok := strings.Cut(fqcn, ".")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, ".") {
+		return nil, fmt.Errorf("invalid collection name %s, expected namespace.name", fqcn)
+	}
+
+	version := config.CollectionVersion
+	if version == "" {
+		version = DEFAULT_COLLECTION_VERSION
+	}
+
+	dependencies := map[string]string{}
+	if fqcn != DEFAULT_COLLECTION {
+		dependencies[DEFAULT_COLLECTION] = "*"
+	}
+
+	return &Collection{
+		Namespace:    namespace,
+		Name:         name,
+		Version:      version,
+		Dependencies: dependencies,
+	}, nil
+}
+
+func (c *Collection) String() string {
+	return fmt.Sprintf("%s.%s", c.Namespace, c.Name)
+}
+
+// DependencyNames returns the collections this one depends on, sorted
+func (c *Collection) DependencyNames() []string {
+	names := make([]string, 0, len(c.Dependencies))
+	for name := range c.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Runtime holds the contents of the collection meta/runtime.yml
+type Runtime struct {
+	RequiresAnsible string
+	Modules         []string
+
+	// Deprecations are the deprecated modules by name, rendered as their
+	// plugin_routing deprecation
+	Deprecations map[string]*Deprecated
+}
+
+// NewRuntime is a constructor that returns the Runtime of the collection
+// with the given (generated) modules in the gcp action group
+func NewRuntime(config *Config, modules []*Module) *Runtime {
+	requiresAnsible := config.RequiresAnsible
+	if requiresAnsible == "" {
+		requiresAnsible = DEFAULT_REQUIRES_ANSIBLE
+	}
+
+	names := make([]string, 0, len(modules))
+	deprecations := map[string]*Deprecated{}
+	for _, m := range modules {
+		names = append(names, m.Name)
+		if m.Deprecated != nil {
+			deprecations[m.Name] = m.Deprecated
+		}
+	}
+	sort.Strings(names)
+
+	return &Runtime{
+		RequiresAnsible: requiresAnsible,
+		Modules:         names,
+		Deprecations:    deprecations,
+	}
+}
+
+// ModuleUtils holds the data of the shared module_utils (gcp_utils.py) the
+// generated modules and lookup plugins import
+type ModuleUtils struct {
+	Collection string
+	AuthKinds  []string
+}
+
+// NewModuleUtils is a constructor that returns the ModuleUtils of the collection
+func NewModuleUtils(config *Config) *ModuleUtils {
+	return &ModuleUtils{
+		Collection: config.Collection,
+		AuthKinds:  AUTH_KINDS,
+	}
+}
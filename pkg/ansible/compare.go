@@ -0,0 +1,121 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// documentationRegexp extracts the DOCUMENTATION string from a python module
+var documentationRegexp = regexp.MustCompile(`(?s)DOCUMENTATION\s*=\s*r?("""|''')(.*?)("""|''')`)
+
+// ModuleDiff holds the interface differences between a published module and
+// its freshly generated counterpart, option names use dot notation for suboptions
+type ModuleDiff struct {
+	// AddedOptions are options that only exist in the generated module
+	AddedOptions []string
+
+	// RemovedOptions are options that only exist in the published module
+	RemovedOptions []string
+
+	// NewlyRequired are options that were optional and are now required
+	NewlyRequired []string
+
+	// TypeChanges describe options whose type or elements changed
+	TypeChanges []string
+
+	// RemovedChoices describe choices no longer accepted by an option
+	RemovedChoices []string
+}
+
+// Breaking returns true when the differences would break existing playbooks
+func (md *ModuleDiff) Breaking() bool {
+	return len(md.RemovedOptions) > 0 || len(md.NewlyRequired) > 0 ||
+		len(md.TypeChanges) > 0 || len(md.RemovedChoices) > 0
+}
+
+// existingOption is the subset of a documented option needed to diff interfaces
+type existingOption struct {
+	Type       string                     `yaml:"type"`
+	Elements   string                     `yaml:"elements"`
+	Required   bool                       `yaml:"required"`
+	Choices    []interface{}              `yaml:"choices"`
+	Suboptions map[string]*existingOption `yaml:"suboptions"`
+}
+
+// CompareWithExisting parses the DOCUMENTATION block of an already published
+// module and diffs its options against the ones of the generated module
+func CompareWithExisting(module *Module, existingModulePath string) (ModuleDiff, error) {
+	diff := ModuleDiff{}
+
+	contents, err := os.ReadFile(existingModulePath)
+	if err != nil {
+		return diff, fmt.Errorf("cannot read existing module %s: %v", existingModulePath, err)
+	}
+
+	match := documentationRegexp.FindSubmatch(contents)
+	if match == nil {
+		return diff, fmt.Errorf("no DOCUMENTATION block found in %s", existingModulePath)
+	}
+
+	doc := struct {
+		Options map[string]*existingOption `yaml:"options"`
+	}{}
+	if err := yaml.Unmarshal(match[2], &doc); err != nil {
+		return diff, fmt.Errorf("cannot parse DOCUMENTATION block in %s: %v", existingModulePath, err)
+	}
+
+	diffOptions(&diff, "", doc.Options, module.Documentation.Options)
+
+	sort.Strings(diff.AddedOptions)
+	sort.Strings(diff.RemovedOptions)
+	sort.Strings(diff.NewlyRequired)
+	sort.Strings(diff.TypeChanges)
+	sort.Strings(diff.RemovedChoices)
+
+	return diff, nil
+}
+
+// diffOptions recursively compares the existing options with the generated ones
+func diffOptions(diff *ModuleDiff, prefix string, existing map[string]*existingOption, generated map[string]*Option) {
+	for name, old := range existing {
+		path := prefix + name
+		option, ok := generated[name]
+		if !ok {
+			diff.RemovedOptions = append(diff.RemovedOptions, path)
+			continue
+		}
+
+		if option.Required && !old.Required {
+			diff.NewlyRequired = append(diff.NewlyRequired, path)
+		}
+		if old.Type != "" && old.Type != option.Type.String() {
+			diff.TypeChanges = append(diff.TypeChanges, fmt.Sprintf("%s: type %s -> %s", path, old.Type, option.Type))
+		}
+		if old.Elements != "" && old.Elements != option.Elements.String() {
+			diff.TypeChanges = append(diff.TypeChanges, fmt.Sprintf("%s: elements %s -> %s", path, old.Elements, option.Elements))
+		}
+		if len(option.Choices) > 0 {
+			for _, choice := range old.Choices {
+				if !slices.Contains(option.Choices, fmt.Sprintf("%v", choice)) {
+					diff.RemovedChoices = append(diff.RemovedChoices, fmt.Sprintf("%s: %v", path, choice))
+				}
+			}
+		}
+
+		diffOptions(diff, path+".", old.Suboptions, option.Suboptions)
+	}
+
+	for name := range generated {
+		if _, ok := existing[name]; !ok {
+			diff.AddedOptions = append(diff.AddedOptions, prefix+name)
+		}
+	}
+}
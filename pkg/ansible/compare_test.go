@@ -0,0 +1,48 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareWithExisting(t *testing.T) {
+	config := &Option{Name: "config", Type: TypeDict, Suboptions: map[string]*Option{
+		"count":   {Name: "count", Type: TypeInt},
+		"enabled": {Name: "enabled", Type: TypeBool},
+	}}
+	module := &Module{Documentation: &Documentation{Options: map[string]*Option{
+		"name":    {Name: "name", Type: TypeStr, Required: true},
+		"size":    {Name: "size", Type: TypeStr, Choices: []string{"SMALL", "MEDIUM"}},
+		"labels":  {Name: "labels", Type: TypeDict, Required: true},
+		"config":  config,
+		"project": {Name: "project", Type: TypeStr},
+	}}}
+
+	diff, err := CompareWithExisting(module, "testdata/gcp_widgets_widget.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"added", diff.AddedOptions, []string{"config.enabled", "project"}},
+		{"removed", diff.RemovedOptions, []string{"color"}},
+		{"newly required", diff.NewlyRequired, []string{"labels"}},
+		{"type changes", diff.TypeChanges, []string{"config.count: type str -> int"}},
+		{"removed choices", diff.RemovedChoices, []string{"size: LARGE"}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if !diff.Breaking() {
+		t.Errorf("Breaking() = false, want true")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import "fmt"
+
+const (
+	DEFAULT_MAX_RETRIES      = 3
+	DEFAULT_RETRY_DELAY      = 1.0
+	DEFAULT_RETRY_MULTIPLIER = 2.0
+	DEFAULT_COLLECTION       = "google.cloud"
+
+	// DEFAULT_REQUEST_TIMEOUT is the timeout (in seconds) of each API request
+	DEFAULT_REQUEST_TIMEOUT = 30.0
+)
+
+// DEFAULT_SENSITIVE_PATTERNS are the option name substrings that hint the
+// value is a credential, even if MMv1 doesn't flag the property as sensitive
+var DEFAULT_SENSITIVE_PATTERNS = []string{"password", "secret", "private_key", "token"}
+
+// DEFAULT_RETRY_STATUS_CODES are the transient HTTP errors worth retrying
+var DEFAULT_RETRY_STATUS_CODES = []int{429, 500, 502, 503, 504}
+
+// Config holds the generation-time settings shared by all the modules
+// generated in a single run
+type Config struct {
+	// Retry is the policy rendered into the module's request helper
+	Retry *RetryPolicy
+
+	// RequestTimeout is the connect/read timeout (in seconds) of each API
+	// request, unrelated to the long running operations timeout
+	RequestTimeout float64
+
+	// ArgSpecSort is the order of the arguments (and nested options) in the
+	// generated argument spec, see SORT_MODES. When empty the arguments are
+	// in the SortNameStateAlpha order and the nested options alphabetical
+	ArgSpecSort SortMode
+
+	// RecreateImmutable generates a delete/create flow (guarded by the force
+	// option) when an immutable field changes, instead of failing
+	RecreateImmutable bool
+
+	// RequireOnPresent makes the required options outside the resource link
+	// only required with state=present, properties can opt out (or in when
+	// this is off) with the required_on_create override
+	RequireOnPresent bool
+
+	// DefaultReturned is the RETURN 'returned' condition for optional fields,
+	// resources can override it with the default_returned key
+	DefaultReturned string
+
+	// ParentContextLength prefixes nested option descriptions shorter than this
+	// many characters with the parent option name, 0 disables it
+	ParentContextLength int
+
+	// ReturnInvocation documents the standard 'invocation' return value
+	ReturnInvocation bool
+
+	// ExplainConflicts replaces the top-level mutually_exclusive argument spec
+	// check with one failing with a descriptive message
+	ExplainConflicts bool
+
+	// VersionAdded is the collection version the modules were added in
+	VersionAdded string
+
+	// VersionAddedCollection qualifies version_added with the collection the
+	// modules were first released in, for modules moved between collections
+	VersionAddedCollection string
+
+	// MaxParagraphs truncates option descriptions longer than this many
+	// paragraphs with a pointer to the API documentation, 0 disables it
+	MaxParagraphs int
+
+	// Collection is the collection the modules are generated for
+	Collection string
+
+	// CollectionVersion is the version of the collection, used along with
+	// Collection to identify the modules in the User-Agent header
+	CollectionVersion string
+
+	// RequiresAnsible is the Ansible version constraint of the collection
+	// (meta/runtime.yml requires_ansible)
+	RequiresAnsible string
+
+	// ValidateScopes checks the zone/region of the resource exists in the
+	// project before calling the API (skipped in check mode)
+	ValidateScopes bool
+
+	// SensitivePatterns are the option name substrings that set no_log on
+	// the option, properties can opt out with the no_log override key
+	SensitivePatterns []string
+}
+
+// requestTimeout returns the configured request timeout, the default when unset
+func requestTimeout(config *Config) float64 {
+	if config.RequestTimeout <= 0 {
+		return DEFAULT_REQUEST_TIMEOUT
+	}
+	return config.RequestTimeout
+}
+
+// userAgent builds the User-Agent out of the collection name and version
+func userAgent(config *Config) string {
+	collection := config.Collection
+	if collection == "" {
+		collection = DEFAULT_COLLECTION
+	}
+	if config.CollectionVersion == "" {
+		return fmt.Sprintf("ansible-%s", collection)
+	}
+	return fmt.Sprintf("ansible-%s/%s", collection, config.CollectionVersion)
+}
+
+// NewConfig is a constructor that returns a Config with sane defaults
+func NewConfig() *Config {
+	return &Config{
+		Retry:             NewRetryPolicy(DEFAULT_MAX_RETRIES, DEFAULT_RETRY_DELAY, DEFAULT_RETRY_MULTIPLIER),
+		RequestTimeout:    DEFAULT_REQUEST_TIMEOUT,
+		DefaultReturned:   DEFAULT_RETURNED,
+		ReturnInvocation:  true,
+		Collection:        DEFAULT_COLLECTION,
+		RequiresAnsible:   DEFAULT_REQUIRES_ANSIBLE,
+		SensitivePatterns: DEFAULT_SENSITIVE_PATTERNS,
+	}
+}
+
+// RetryPolicy describes how the generated module retries transient API errors.
+// The delay before retry N is Delay * Multiplier^(N-1) seconds, so a multiplier
+// of 1 means a constant delay and anything above that backs off exponentially
+type RetryPolicy struct {
+	MaxRetries  int     `json:"max_retries"`
+	Delay       float64 `json:"delay"`
+	Multiplier  float64 `json:"multiplier"`
+	StatusCodes []int   `json:"status_codes"`
+}
+
+// NewRetryPolicy is a constructor that returns a RetryPolicy retrying the
+// default transient status codes
+func NewRetryPolicy(maxRetries int, delay float64, multiplier float64) *RetryPolicy {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	return &RetryPolicy{
+		MaxRetries:  maxRetries,
+		Delay:       delay,
+		Multiplier:  multiplier,
+		StatusCodes: DEFAULT_RETRY_STATUS_CODES,
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CUSTOM_CODE_DIR is the directory (in the template directory) holding the
+// python counterparts of the MMv1 custom code files e.g. the MMv1 encoder
+// templates/terraform/encoders/foo.go.tmpl is read from custom_code/encoders/foo.py
+const CUSTOM_CODE_DIR = "custom_code"
+
+// loadCustomCode returns the python code of a custom code hook. Overrides set
+// the code inline, MMv1 references terraform templates which can't be used
+// as-is so their python counterpart is read from the template directory
+// instead. Empty when the hook isn't set or has no python counterpart, the
+// code always ends with a newline so it can be spliced between other lines
+func (m *Module) loadCustomCode(hook string) string {
+	if hook == "" {
+		return ""
+	}
+	if strings.Contains(hook, "\n") || !strings.HasSuffix(hook, ".tmpl") {
+		return strings.TrimSuffix(hook, "\n") + "\n"
+	}
+
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(hook), ".tmpl"), ".go")
+	codePath := path.Join(m.Resource.TemplateDir, CUSTOM_CODE_DIR, filepath.Base(filepath.Dir(hook)), name+".py")
+	code, err := os.ReadFile(codePath)
+	if err != nil {
+		log.Warn().Msgf("%s: ignoring custom code %s, cannot read %s", m, hook, codePath)
+		return ""
+	}
+
+	return strings.TrimSuffix(string(code), "\n") + "\n"
+}
+
+// CustomImportCode returns the custom imports of the module
+func (m *Module) CustomImportCode() string {
+	return m.loadCustomCode(m.CustomCode().CustomImport)
+}
+
+// ConstantsCode returns the custom module level code, placed after the imports
+func (m *Module) ConstantsCode() string {
+	return m.loadCustomCode(m.CustomCode().Constants)
+}
+
+// EncoderCode returns the custom code transforming the request before it is
+// sent, it is given the request dict and should change (or replace) it
+func (m *Module) EncoderCode() string {
+	return m.loadCustomCode(m.CustomCode().Encoder)
+}
+
+// UpdateEncoderCode returns the custom code transforming the update request,
+// the update uses the encoder when not set
+func (m *Module) UpdateEncoderCode() string {
+	return m.loadCustomCode(m.CustomCode().UpdateEncoder)
+}
+
+// DecoderCode returns the custom code transforming the API object after it is
+// read, it is given the response dict and should change (or replace) it
+func (m *Module) DecoderCode() string {
+	return m.loadCustomCode(m.CustomCode().Decoder)
+}
+
+// PreCreateCode returns the custom code run before the create call
+func (m *Module) PreCreateCode() string {
+	return m.loadCustomCode(m.CustomCode().PreCreate)
+}
+
+// PostCreateCode returns the custom code run after the create call succeeds
+func (m *Module) PostCreateCode() string {
+	return m.loadCustomCode(m.CustomCode().PostCreate)
+}
+
+// PreUpdateCode returns the custom code run before the update call
+func (m *Module) PreUpdateCode() string {
+	return m.loadCustomCode(m.CustomCode().PreUpdate)
+}
+
+// PostUpdateCode returns the custom code run after the update call succeeds
+func (m *Module) PostUpdateCode() string {
+	return m.loadCustomCode(m.CustomCode().PostUpdate)
+}
+
+// PreDeleteCode returns the custom code run before the delete call
+func (m *Module) PreDeleteCode() string {
+	return m.loadCustomCode(m.CustomCode().PreDelete)
+}
+
+// PostDeleteCode returns the custom code run after the delete call succeeds
+func (m *Module) PostDeleteCode() string {
+	return m.loadCustomCode(m.CustomCode().PostDelete)
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncoderCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		customCode string
+		python     string
+		want       string
+	}{
+		{
+			name:       "inline",
+			customCode: "custom_code:\n  encoder: |\n    request[\"size\"] = \"L\"\n",
+			want:       "request[\"size\"] = \"L\"\n",
+		},
+		{
+			name:       "template",
+			customCode: "custom_code:\n  encoder: templates/terraform/encoders/widget.go.tmpl\n",
+			python:     "request[\"size\"] = \"XL\"",
+			want:       "request[\"size\"] = \"XL\"\n",
+		},
+		{
+			name:       "template without python",
+			customCode: "custom_code:\n  encoder: templates/terraform/encoders/widget.go.tmpl\n",
+			want:       "",
+		},
+		{
+			name: "absent",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.customCode + testResourceYAML}, "Widget", nil)
+			m.Resource.TemplateDir = t.TempDir()
+			if tt.python != "" {
+				dir := filepath.Join(m.Resource.TemplateDir, CUSTOM_CODE_DIR, "encoders")
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "widget.py"), []byte(tt.python), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := m.EncoderCode(); got != tt.want {
+				t.Errorf("EncoderCode() = %q, want %q", got, tt.want)
+			}
+			for hook, code := range map[string]string{
+				"decoder":     m.DecoderCode(),
+				"post_create": m.PostCreateCode(),
+				"pre_delete":  m.PreDeleteCode(),
+			} {
+				if code != "" {
+					t.Errorf("unset %s hook = %q, want it empty", hook, code)
+				}
+			}
+		})
+	}
+}
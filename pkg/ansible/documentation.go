@@ -5,11 +5,19 @@ package ansible
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
+	"github.com/rs/zerolog/log"
 	"github.com/thekad/magic-ansible/pkg/api"
 )
 
+// MAX_SHORT_DESCRIPTION_LENGTH is the longest short_description we emit,
+// longer ones get truncated on a word boundary
+const MAX_SHORT_DESCRIPTION_LENGTH = 80
+
 var STANDARD_MODULE_REQUIREMENTS = []string{
 	"python >= 3.8",
 	"requests >= 2.18.4",
@@ -27,6 +35,12 @@ type Documentation struct {
 	// Detailed description - string or list of strings
 	Description []string `yaml:"description"`
 
+	// VersionAdded is the collection version the module was added in
+	VersionAdded string `yaml:"version_added,omitempty"`
+
+	// VersionAddedCollection is the collection VersionAdded refers to
+	VersionAddedCollection string `yaml:"version_added_collection,omitempty"`
+
 	// Author information - string or list of strings
 	Author []string `yaml:"author,omitempty"`
 
@@ -39,10 +53,117 @@ type Documentation struct {
 	// Notes about the module
 	Notes []string `yaml:"notes,omitempty"`
 
+	// SeeAlso are references to related modules and documentation
+	SeeAlso []*SeeAlso `yaml:"seealso,omitempty"`
+
+	// Attributes are the capabilities of the module e.g. check_mode
+	Attributes map[string]*Attribute `yaml:"attributes,omitempty"`
+
+	// Deprecated is set when the module is deprecated
+	Deprecated *Deprecated `yaml:"deprecated,omitempty"`
+
 	// DocFragments are fragments of shared documentation that will be included in the documentation
 	DocFragments []string `yaml:"extends_documentation_fragment,omitempty"`
 }
 
+// SeeAlso is an entry of the seealso block, either a module reference or a
+// named link
+type SeeAlso struct {
+	Module      string `yaml:"module,omitempty"`
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Link        string `yaml:"link,omitempty"`
+}
+
+// Attribute is an entry of the attributes block, the support level of a
+// module capability
+type Attribute struct {
+	Description string `yaml:"description"`
+	Support     string `yaml:"support"`
+	Details     string `yaml:"details,omitempty"`
+	Platforms   string `yaml:"platforms,omitempty"`
+}
+
+// ATTRIBUTE_SUPPORT_LEVELS are the valid support levels of an attribute
+var ATTRIBUTE_SUPPORT_LEVELS = []string{"full", "partial", "none", "N/A"}
+
+// newAttributes returns the attributes of the given resource module, the
+// defaults (check_mode full, diff_mode none, platform posix) with the support
+// levels of the attributes resource override applied
+func newAttributes(resource *api.Resource) map[string]*Attribute {
+	attributes := map[string]*Attribute{
+		"check_mode": {
+			Description: "Can run in check_mode and return changed status prediction without modifying target.",
+			Support:     "full",
+		},
+		"diff_mode": {
+			Description: "Will return details on what has changed (or possibly needs changing in check_mode), when in diff mode.",
+			Support:     "none",
+		},
+		"platform": {
+			Description: "Target OS/families that can be operated against.",
+			Support:     "N/A",
+			Platforms:   "posix",
+		},
+	}
+	if resource.Overrides == nil {
+		return attributes
+	}
+	for name, support := range resource.Overrides.Attributes {
+		attribute, ok := attributes[name]
+		if !ok {
+			log.Warn().Msgf("resource %s overrides unknown attribute %s, ignoring it", resource.Name, name)
+			continue
+		}
+		if !slices.Contains(ATTRIBUTE_SUPPORT_LEVELS, support) {
+			log.Warn().Msgf("resource %s attribute %s support must be one of %v, ignoring %s", resource.Name, name, ATTRIBUTE_SUPPORT_LEVELS, support)
+			continue
+		}
+		attribute.Support = support
+	}
+	return attributes
+}
+
+// Deprecated is the deprecated block of a module documentation
+type Deprecated struct {
+	RemovedIn   string `yaml:"removed_in"`
+	Why         string `yaml:"why"`
+	Alternative string `yaml:"alternative"`
+}
+
+// NewDeprecated returns the deprecation of the given resource from the
+// deprecated override, nil when the module isn't deprecated
+func NewDeprecated(resource *api.Resource) *Deprecated {
+	if resource.Overrides == nil || resource.Overrides.Deprecated == nil {
+		return nil
+	}
+	override := resource.Overrides.Deprecated
+	deprecated := &Deprecated{
+		RemovedIn:   override.RemovedIn,
+		Why:         override.Why,
+		Alternative: override.Alternative,
+	}
+	if deprecated.Why == "" {
+		deprecated.Why = strings.TrimSpace(resource.Mmv1.DeprecationMessage)
+	}
+	if deprecated.RemovedIn == "" {
+		log.Warn().Msgf("deprecated resource %s has no removed_in version", resource.Name)
+	}
+	if deprecated.Why == "" {
+		log.Warn().Msgf("deprecated resource %s has no reason (why)", resource.Name)
+	}
+	if deprecated.Alternative == "" {
+		deprecated.Alternative = "There is no alternative."
+	}
+	return deprecated
+}
+
+// WarningText returns the reason and the alternative as a single sentence,
+// the meta/runtime.yml deprecation warning_text
+func (d *Deprecated) WarningText() string {
+	return strings.TrimSpace(strings.Join(google.Reject([]string{d.Why, d.Alternative}, func(s string) bool { return s == "" }), " "))
+}
+
 // NewDocumentationFromOptions creates a new Documentation from a resource and options
 func NewDocumentationFromOptions(resource *api.Resource, options map[string]*Option) *Documentation {
 	resourceNotes := []string{
@@ -54,19 +175,67 @@ func NewDocumentationFromOptions(resource *api.Resource, options map[string]*Opt
 	docFragments := []string{
 		"google.cloud.gcp",
 	}
+	seeAlso := []*SeeAlso{}
+	if resource.Mmv1.References.Api != "" {
+		seeAlso = append(seeAlso, &SeeAlso{
+			Name:        "GCP API documentation",
+			Description: fmt.Sprintf("REST API reference for the %s resource.", resource.Mmv1.Name),
+			Link:        resource.Mmv1.References.Api,
+		})
+	}
 	authors := []string{"Google Inc. (@googlecloudplatform)"}
 	return &Documentation{
 		Module:           resource.AnsibleName(),
 		Author:           authors,
-		ShortDescription: fmt.Sprintf("Creates a GCP %s.%s resource", resource.Parent.Mmv1.Name, resource.Mmv1.Name),
+		ShortDescription: cleanShortDescription(fmt.Sprintf("Creates a GCP %s.%s resource", resource.Parent.Mmv1.Name, resource.Mmv1.Name)),
 		Description:      cleanModuleDescription(resource.Mmv1.Description),
 		Options:          options,
 		Requirements:     STANDARD_MODULE_REQUIREMENTS,
 		Notes:            resourceNotes,
+		SeeAlso:          seeAlso,
+		Attributes:       newAttributes(resource),
+		Deprecated:       NewDeprecated(resource),
 		DocFragments:     docFragments,
 	}
 }
 
+// siblingSeeAlso returns the seealso entries of the other resources of the
+// same product, sorted by module name
+func siblingSeeAlso(resource *api.Resource, collection string) []*SeeAlso {
+	siblings := []*SeeAlso{}
+	for _, sibling := range resource.Parent.Resources {
+		if sibling == resource || sibling.AnsibleName() == resource.AnsibleName() {
+			continue
+		}
+		siblings = append(siblings, &SeeAlso{
+			Module:      fmt.Sprintf("%s.%s", collection, sibling.AnsibleName()),
+			Description: fmt.Sprintf("Creates a GCP %s.%s resource.", resource.Parent.Mmv1.Name, sibling.Name),
+		})
+	}
+	sort.Slice(siblings, func(i, j int) bool {
+		return siblings[i].Module < siblings[j].Module
+	})
+	return siblings
+}
+
+// cleanShortDescription makes the given text a valid short_description: no
+// trailing period and no longer than MAX_SHORT_DESCRIPTION_LENGTH characters
+func cleanShortDescription(description string) string {
+	description = strings.TrimRight(strings.TrimSpace(description), ".")
+	if len(description) <= MAX_SHORT_DESCRIPTION_LENGTH {
+		return description
+	}
+
+	truncated := description[:MAX_SHORT_DESCRIPTION_LENGTH]
+	if i := strings.LastIndex(truncated, " "); i > 0 {
+		truncated = truncated[:i]
+	}
+	truncated = strings.TrimRight(strings.TrimSpace(truncated), ".,;:")
+	log.Warn().Msgf("short description truncated to %d characters: %s", MAX_SHORT_DESCRIPTION_LENGTH, truncated)
+
+	return truncated
+}
+
 func cleanModuleDescription(description string) []string {
 	var cleanLines []string
 	for _, line := range strings.Split(description, "\n") {
@@ -80,5 +249,5 @@ func cleanModuleDescription(description string) []string {
 
 // Show the documentation as a YAML string
 func (d *Documentation) ToString() string {
-	return ToYAML(d)
+	return ToYAML(d, PREFERRED_OPTION_KEY_ORDER...)
 }
@@ -0,0 +1,58 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanShortDescription(t *testing.T) {
+	long := "Creates a GCP Very Long Product Name Service Resource With An Extraordinarily Descriptive Name"
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{"short", "Creates a GCP Widget", "Creates a GCP Widget"},
+		{"trailing period", " Creates a GCP Widget. ", "Creates a GCP Widget"},
+		{"long", long, "Creates a GCP Very Long Product Name Service Resource With An Extraordinarily"},
+		{"long with punctuation", "Creates a GCP widget, the " + strings.Repeat("x", 50) + ", and " + strings.Repeat("y", 20), "Creates a GCP widget, the " + strings.Repeat("x", 50)},
+		{"no word boundary", strings.Repeat("x", 100), strings.Repeat("x", MAX_SHORT_DESCRIPTION_LENGTH)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cleanShortDescription(tt.description)
+			if got != tt.want {
+				t.Errorf("cleanShortDescription(%q) = %q, want %q", tt.description, got, tt.want)
+			}
+			if len(got) > MAX_SHORT_DESCRIPTION_LENGTH || strings.HasSuffix(got, ".") {
+				t.Errorf("%q isn't a valid short_description", got)
+			}
+		})
+	}
+}
+
+func TestApiSeeAlso(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     bool
+	}{
+		{"api reference", "references:\n  api: https://cloud.google.com/widgets/docs/reference/rest\n" + testResourceYAML, true},
+		{"no api reference", testResourceYAML, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			doc := m.Documentation.ToString()
+			want := "  - description: REST API reference for the Widget resource.\n    link: https://cloud.google.com/widgets/docs/reference/rest\n    name: GCP API documentation\n"
+			if got := strings.Contains(doc, want); got != tt.want {
+				t.Errorf("API documentation in seealso is %v, want %v:\n%s", got, tt.want, doc)
+			}
+		})
+	}
+}
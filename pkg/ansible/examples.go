@@ -4,11 +4,15 @@
 package ansible
 
 import (
+	"bytes"
 	"fmt"
+	"regexp"
 	"strings"
 
 	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
 	mmv1resource "github.com/GoogleCloudPlatform/magic-modules/mmv1/api/resource"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
 )
 
 type Examples struct {
@@ -33,18 +37,113 @@ func NewExamplesFromMmv1(mmv1 *mmv1api.Resource) *Examples {
 	}
 }
 
-func (e *Examples) ToString(which string) string {
-	separator := fmt.Sprintf("\n%s\n\n", strings.Repeat("#", 80))
-	exampleStrings := []string{}
-	examples := []mmv1resource.Examples{}
+// EXAMPLE_SEPARATOR separates the examples of a module
+var EXAMPLE_SEPARATOR = fmt.Sprintf("\n%s\n\n", strings.Repeat("#", 80))
+
+// byKind returns the doc or test examples
+func (e *Examples) byKind(which string) []mmv1resource.Examples {
 	switch which {
 	case "doc":
-		examples = e.DocExamples
+		return e.DocExamples
 	case "test":
-		examples = e.TestExamples
+		return e.TestExamples
+	}
+	return []mmv1resource.Examples{}
+}
+
+// testVarRegexp matches the test values MMv1 gives to the example vars e.g.
+// tf-test-my-cluster%{random_suffix}
+var testVarRegexp = regexp.MustCompile(`tf([-_])test[-_]([\w-]*?)%\{random_suffix\}`)
+
+// interpolationRegexp matches the rest of the MMv1 test interpolations e.g. %{org_id}
+var interpolationRegexp = regexp.MustCompile(`%\{(\w+)\}`)
+
+// RenderVars returns the example text with the MMv1 test interpolations
+// replaced by Jinja variables: the example vars get their example value (plus
+// the resource_name prefix in tests) and the test env vars / overrides become
+// gcp_<name> variables
+func (e *Examples) RenderVars(example mmv1resource.Examples, which string) string {
+	text := testVarRegexp.ReplaceAllStringFunc(example.TestHCLText, func(match string) string {
+		groups := testVarRegexp.FindStringSubmatch(match)
+		if which == "test" {
+			return fmt.Sprintf("{{ resource_name }}%s%s", groups[1], groups[2])
+		}
+		return groups[2]
+	})
+	// vars without a tf-test prefix (e.g. descriptions) just lose the suffix
+	text = strings.ReplaceAll(text, "%{random_suffix}", "")
+
+	return interpolationRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		name := interpolationRegexp.FindStringSubmatch(match)[1]
+		_, env := example.TestEnvVars[name]
+		_, override := example.TestVarsOverrides[name]
+		if !env && !override {
+			log.Warn().Msgf("example %s references undeclared variable %s", example.Name, name)
+			return fmt.Sprintf("{{ %s }}", name)
+		}
+		return fmt.Sprintf("{{ gcp_%s }}", name)
+	})
+}
+
+func (e *Examples) ToString(which string) string {
+	exampleStrings := []string{}
+	for _, example := range e.byKind(which) {
+		exampleStrings = append(exampleStrings, e.RenderVars(example, which))
 	}
-	for _, example := range examples {
-		exampleStrings = append(exampleStrings, example.TestHCLText)
+	return strings.Join(exampleStrings, EXAMPLE_SEPARATOR)
+}
+
+// ToAnsibleTasks is like ToString but the examples that are Terraform HCL
+// (i.e. not Ansible tasks already) are converted to gcp_* module tasks
+func (e *Examples) ToAnsibleTasks(which string) string {
+	exampleStrings := []string{}
+	for _, example := range e.byKind(which) {
+		text := e.RenderVars(example, which)
+		tasks := []interface{}{}
+		if err := yaml.Unmarshal([]byte(text), &tasks); err == nil {
+			exampleStrings = append(exampleStrings, text)
+			continue
+		}
+
+		converted, err := hclToTasks(text)
+		if err != nil {
+			log.Warn().Msgf("cannot convert example %s to Ansible tasks, keeping it as-is: %v", example.Name, err)
+			exampleStrings = append(exampleStrings, text)
+			continue
+		}
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(converted); err != nil {
+			log.Warn().Msgf("cannot encode example %s: %v", example.Name, err)
+			exampleStrings = append(exampleStrings, text)
+			continue
+		}
+		encoder.Close()
+		exampleStrings = append(exampleStrings, buf.String())
 	}
-	return strings.Join(exampleStrings, separator)
+	return strings.Join(exampleStrings, EXAMPLE_SEPARATOR)
+}
+
+// DocParams returns the parameters the first doc example passes to the given
+// module (e.g. google.cloud.gcp_alloydb_cluster), nil if there's none
+func (e *Examples) DocParams(moduleName string) map[string]interface{} {
+	if len(e.DocExamples) == 0 {
+		return nil
+	}
+
+	tasks := []map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(e.RenderVars(e.DocExamples[0], "doc")), &tasks); err != nil {
+		log.Debug().Msgf("cannot parse example %s: %v", e.DocExamples[0].Name, err)
+		return nil
+	}
+	for _, task := range tasks {
+		for _, key := range []string{moduleName, "google.cloud." + moduleName} {
+			if params, ok := task[key].(map[string]interface{}); ok {
+				return params
+			}
+		}
+	}
+
+	return nil
 }
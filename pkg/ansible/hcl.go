@@ -0,0 +1,340 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
+	"gopkg.in/yaml.v3"
+)
+
+// hclBlock is a (very) small subset of an HCL block e.g.
+// resource "google_alloydb_cluster" "default" { ... }
+type hclBlock struct {
+	Type   string
+	Labels []string
+	Body   *yaml.Node
+}
+
+// hclParser parses the common cases of Terraform examples: blocks, nested
+// blocks and attributes with string, number, bool, list and object values.
+// Anything else (references, function calls, interpolations) is kept as the
+// raw expression string
+type hclParser struct {
+	text string
+	pos  int
+}
+
+// parseHCL returns the top-level blocks of the given HCL text
+func parseHCL(text string) ([]*hclBlock, error) {
+	p := &hclParser{text: text}
+	blocks := []*hclBlock{}
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return blocks, nil
+		}
+		blockType := p.ident()
+		if blockType == "" {
+			return nil, p.errorf("expected a block")
+		}
+		block, err := p.block(blockType)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// block parses the labels and body of a block whose type was already read
+func (p *hclParser) block(blockType string) (*hclBlock, error) {
+	block := &hclBlock{Type: blockType}
+	for {
+		p.skipSpace(false)
+		if p.peek() != '"' {
+			break
+		}
+		label, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		block.Labels = append(block.Labels, label)
+	}
+	if !p.consume('{') {
+		return nil, p.errorf("expected { after block %s", blockType)
+	}
+	body, err := p.body()
+	if err != nil {
+		return nil, err
+	}
+	block.Body = body
+	return block, nil
+}
+
+// body parses attributes and nested blocks up to the closing brace, nested
+// blocks repeated more than once become a list
+func (p *hclParser) body() (*yaml.Node, error) {
+	body := &yaml.Node{Kind: yaml.MappingNode}
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated block")
+		}
+		if p.consume('}') {
+			return body, nil
+		}
+		name := p.ident()
+		if name == "" {
+			return nil, p.errorf("expected an attribute or a block")
+		}
+		p.skipSpace(false)
+		if p.consume('=') {
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			setMappingValue(body, google.Underscore(name), value, false)
+			continue
+		}
+		nested, err := p.block(name)
+		if err != nil {
+			return nil, err
+		}
+		setMappingValue(body, google.Underscore(name), nested.Body, true)
+	}
+}
+
+// value parses an attribute value
+func (p *hclParser) value() (*yaml.Node, error) {
+	p.skipSpace(false)
+	switch c := p.peek(); {
+	case c == '"':
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}, nil
+	case c == '[':
+		p.pos++
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for {
+			p.skipSpace(true)
+			if p.consume(']') {
+				return list, nil
+			}
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list.Content = append(list.Content, item)
+			p.skipSpace(true)
+			p.consume(',')
+		}
+	case c == '{':
+		p.pos++
+		object := &yaml.Node{Kind: yaml.MappingNode}
+		for {
+			p.skipSpace(true)
+			if p.consume('}') {
+				return object, nil
+			}
+			var key string
+			if p.peek() == '"' {
+				k, err := p.quoted()
+				if err != nil {
+					return nil, err
+				}
+				key = k
+			} else {
+				key = p.ident()
+			}
+			if key == "" {
+				return nil, p.errorf("expected an object key")
+			}
+			p.skipSpace(false)
+			if !p.consume('=') && !p.consume(':') {
+				return nil, p.errorf("expected = after object key %s", key)
+			}
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			// object keys (e.g. labels) are data, not attribute names
+			setMappingValue(object, key, item, false)
+			p.skipSpace(true)
+			p.consume(',')
+		}
+	default:
+		// TODO: references (google_x.y.id) and function calls are kept as
+		// the raw expression, they need to be replaced by hand
+		expression := p.expression()
+		if expression == "" {
+			return nil, p.errorf("expected a value")
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: expression}
+		if _, err := strconv.ParseFloat(expression, 64); err == nil {
+			node.Tag = "!!int"
+			if strings.ContainsAny(expression, ".eE") {
+				node.Tag = "!!float"
+			}
+		} else if expression == "true" || expression == "false" {
+			node.Tag = "!!bool"
+		} else {
+			node.Tag = "!!str"
+		}
+		return node, nil
+	}
+}
+
+// quoted parses a double-quoted string, interpolations (${...}) are kept as-is
+func (p *hclParser) quoted() (string, error) {
+	start := p.pos
+	p.pos++
+	for !p.eof() {
+		switch p.text[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			s, err := strconv.Unquote(p.text[start:p.pos])
+			if err != nil {
+				return p.text[start+1 : p.pos-1], nil
+			}
+			return s, nil
+		case '\n':
+			return "", p.errorf("unterminated string")
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// expression reads a bare value up to the end of the line, a comma or the
+// closing bracket of the enclosing list/object (parentheses are balanced)
+func (p *hclParser) expression() string {
+	start := p.pos
+	depth := 0
+	for !p.eof() {
+		c := p.text[p.pos]
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			depth--
+		} else if depth == 0 && (c == '\n' || c == ',' || c == ']' || c == '}' || c == '#') {
+			break
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.text[start:p.pos])
+}
+
+// ident reads an identifier (letters, digits, _ and -)
+func (p *hclParser) ident() string {
+	start := p.pos
+	for !p.eof() {
+		r := rune(p.text[p.pos])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+// skipSpace skips blanks and comments, newlines too when multiline is set
+func (p *hclParser) skipSpace(multiline bool) {
+	for !p.eof() {
+		c := p.text[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || (multiline && c == '\n'):
+			p.pos++
+		case c == '#' || strings.HasPrefix(p.text[p.pos:], "//"):
+			for !p.eof() && p.text[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *hclParser) consume(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *hclParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func (p *hclParser) eof() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *hclParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.text[:min(p.pos, len(p.text))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// setMappingValue sets the key of a mapping node, when the key already exists
+// and asList is set the values are collected in a list (repeated HCL blocks)
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node, asList bool) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if !asList {
+			mapping.Content[i+1] = value
+			return
+		}
+		existing := mapping.Content[i+1]
+		if existing.Kind != yaml.SequenceNode {
+			existing = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{existing}}
+			mapping.Content[i+1] = existing
+		}
+		existing.Content = append(existing.Content, value)
+		return
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// hclToTasks converts the resource blocks of an HCL example to Ansible tasks
+// creating them with the matching gcp_* modules, other blocks are skipped
+func hclToTasks(text string) (*yaml.Node, error) {
+	blocks, err := parseHCL(text)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, block := range blocks {
+		if block.Type != "resource" || len(block.Labels) != 2 {
+			continue
+		}
+		moduleName := "google.cloud." + strings.Replace(block.Labels[0], "google_", "gcp_", 1)
+		setMappingValue(block.Body, "state", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "present"}, false)
+
+		task := &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(task, "name", &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Tag:   "!!str",
+			Value: fmt.Sprintf("Create %s %s", strings.ReplaceAll(strings.TrimPrefix(block.Labels[0], "google_"), "_", " "), block.Labels[1]),
+		}, false)
+		setMappingValue(task, moduleName, block.Body, false)
+		tasks.Content = append(tasks.Content, task)
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
+	"github.com/thekad/magic-ansible/pkg/api"
+)
+
+// Lookup is a read-only lookup plugin that lists (or reads) the resources of
+// a single product, e.g. plugins/lookup/gcp_alloydb.py
+type Lookup struct {
+	Name           string
+	Product        *api.Product
+	Resources      map[string]*LookupResource
+	RetryPolicy    *RetryPolicy
+	RequestTimeout float64
+	UserAgent      string
+}
+
+// LookupResource holds what the lookup plugin needs to query one resource kind
+type LookupResource struct {
+	// Endpoint is the product base URL as a python format string
+	Endpoint string `json:"endpoint"`
+
+	// ListUri is the collection URI, used when the self link can't be built
+	ListUri string `json:"list_uri"`
+
+	// ReadUri is the self link URI (same as the module's read operation), used
+	// when all of its parameters are given
+	ReadUri string `json:"read_uri"`
+
+	// ItemsKey is the key holding the resources in a list response
+	ItemsKey string `json:"items_key"`
+
+	// Kind is the resource kind, as passed to the module_utils Resource
+	Kind string `json:"kind"`
+
+	// Regional is true when the endpoint host has a region placeholder, see
+	// RegionalResources
+	Regional bool `json:"-"`
+}
+
+// NewLookupFromModules creates a lookup plugin for the given product out of
+// its generated modules, one resource kind per module
+func NewLookupFromModules(product *api.Product, modules []*Module, config *Config) *Lookup {
+	if config == nil {
+		config = NewConfig()
+	}
+	l := &Lookup{
+		Name:           product.AnsibleName(),
+		Product:        product,
+		Resources:      map[string]*LookupResource{},
+		RetryPolicy:    config.Retry,
+		RequestTimeout: requestTimeout(config),
+		UserAgent:      userAgent(config),
+	}
+
+	for _, m := range modules {
+		if m.Resource.Parent != product {
+			continue
+		}
+		l.Resources[google.Underscore(m.Resource.Name)] = &LookupResource{
+			Endpoint: m.EndpointTemplate(),
+			ListUri:  m.ListUri(),
+			ReadUri:  m.OperationConfigs["read"].UriTemplate,
+			ItemsKey: m.ListItemsKey(),
+			Kind:     m.Kind(),
+			Regional: m.RegionalEndpoint(),
+		}
+	}
+
+	return l
+}
+
+func (l *Lookup) String() string {
+	return l.Name
+}
+
+// ResourceNames returns the resource kinds supported by the lookup, sorted
+func (l *Lookup) ResourceNames() []string {
+	names := make([]string, 0, len(l.Resources))
+	for name := range l.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegionalResources returns the resource kinds served from a per-region
+// endpoint, sorted
+func (l *Lookup) RegionalResources() []string {
+	names := []string{}
+	for _, name := range l.ResourceNames() {
+		if l.Resources[name].Regional {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Scopes returns the default OAuth scopes of the product
+func (l *Lookup) Scopes() []string {
+	return l.Product.Mmv1.Scopes
+}
+
+// ProductName returns the MMv1 product name
+func (l *Lookup) ProductName() string {
+	return l.Product.Mmv1.Name
+}
+
+// ShortDescription returns the lookup short description
+func (l *Lookup) ShortDescription() string {
+	return cleanShortDescription(fmt.Sprintf("Lists GCP %s resources", l.ProductName()))
+}
+
+// ListUri returns the collection URI of the resource as a python format
+// string, from the list operation
+func (m *Module) ListUri() string {
+	return m.OperationConfigs["list"].UriTemplate
+}
+
+// ListItemsKey returns the key holding the resources in a list response
+func (m *Module) ListItemsKey() string {
+	return m.OperationConfigs["list"].ItemsKey
+}
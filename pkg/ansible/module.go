@@ -4,15 +4,24 @@
 package ansible
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 
 	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
+	"github.com/GoogleCloudPlatform/magic-modules/mmv1/api/product"
 	mmv1resource "github.com/GoogleCloudPlatform/magic-modules/mmv1/api/resource"
 	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
 	"github.com/rs/zerolog/log"
 	"github.com/thekad/magic-ansible/pkg/api"
 )
 
+// linkFieldRegexp matches the {field} placeholders of an operation URI
+var linkFieldRegexp = regexp.MustCompile(`\{(\w+)\}`)
+
 type Module struct {
 	Name             string
 	Resource         *api.Resource
@@ -24,23 +33,122 @@ type Module struct {
 	ArgumentSpec     *ArgumentSpec
 	OperationConfigs map[string]*OperationConfig
 	Dependency       *Dependency
+	Config           *Config
+
+	// Deprecated is set when the module is deprecated, see the deprecated
+	// resource override
+	Deprecated *Deprecated
+
+	// SupportsCheckMode is true (unless overridden) when the module skips the
+	// API writes in check mode, reporting the change it would make
+	SupportsCheckMode bool
 }
 
 // NewFromResource creates a new Module from an API Resource
 // The rule of thumb for this constructor is to build the options, examples,
 // returns, and operation configs from the Mmv1 API Resource object, and then
 // build the rest of the members based off the options.
-func NewFromResource(resource *api.Resource) *Module {
+func NewFromResource(resource *api.Resource, config *Config) *Module {
+	if config == nil {
+		config = NewConfig()
+	}
 	m := &Module{
+		Config:           config,
 		Name:             resource.AnsibleName(),
 		Resource:         resource,
-		Options:          NewOptionsFromMmv1(resource.Mmv1),
+		Options:          NewOptionsFromMmv1(resource.Mmv1, resource.PropertyOverrides),
 		Examples:         NewExamplesFromMmv1(resource.Mmv1),
-		Returns:          NewReturnBlockFromMmv1(resource.Mmv1),
+		Returns:          NewReturnBlockFromMmv1(resource.Mmv1, defaultReturned(resource, config)),
 		OperationConfigs: NewOperationConfigsFromMmv1(resource.Mmv1),
 	}
+	addResourceRefComponents(m.Options, resource.Parent)
+	m.SupportsCheckMode = resource.Overrides == nil || resource.Overrides.SupportsCheckMode == nil || *resource.Overrides.SupportsCheckMode
 	m.Dependency = getDependency(m.Options)
 
+	if resource.Overrides != nil && resource.Overrides.StateDefault != "" {
+		state := resource.Overrides.StateDefault
+		if slices.Contains(m.Options["state"].Choices, state) {
+			m.Options["state"] = newStateOption(state)
+		} else {
+			log.Warn().Msgf("resource %s state_default must be one of %v, ignoring %s", resource.Name, m.Options["state"].Choices, state)
+		}
+	}
+
+	// fields only needed to create the resource shouldn't be required to delete it
+	if relaxed := requireOnPresent(m.Options, m.LinkFields(), resource.PropertyOverrides, config.RequireOnPresent); len(relaxed) > 0 {
+		if m.Dependency == nil {
+			m.Dependency = &Dependency{}
+		}
+		m.Dependency.RequiredIf = append(m.Dependency.RequiredIf, &RequiredIf{Key: "state", Value: "present", Requirements: relaxed})
+	}
+
+	applyCustomDescriptions(m.Returns.Returns, "", resource.PropertyOverrides)
+
+	if include := m.ReturnsInclude(); len(include) > 0 {
+		filterReturns(m.Returns.Returns, include)
+	}
+
+	if params := m.Examples.DocParams(m.Name); params != nil {
+		// the module's own options (e.g. state) aren't resource fields
+		delete(params, "state")
+		delete(params, "force")
+		delete(params, "wait")
+		for name := range newAuthOptions() {
+			delete(params, name)
+		}
+		addReturnSamples(m.Returns.Returns, params)
+	}
+
+	if config.ReturnInvocation {
+		m.Returns.Returns["invocation"] = newInvocationReturn()
+	}
+
+	if m.ExposesRawResponse() {
+		m.Returns.Returns[RAW_RESPONSE_RETURN] = newRawResponseReturn()
+	}
+
+	if config.ParentContextLength > 0 {
+		addParentContext(m.Options, "", config.ParentContextLength)
+	}
+
+	if len(config.SensitivePatterns) > 0 {
+		markSensitiveOptions(m.Options, config.SensitivePatterns)
+	}
+
+	setBetaVersionAdded(m.Options, config.VersionAdded)
+	if config.VersionAddedCollection != "" {
+		setVersionAddedCollection(m.Options, config.VersionAddedCollection)
+	}
+
+	if config.MaxParagraphs > 0 {
+		limitParagraphs(m.Options, config.MaxParagraphs, resource.Mmv1.References.Api)
+	}
+
+	m.checkClassNames()
+
+	if m.IsAsync() {
+		if _, ok := m.Options["wait"]; ok {
+			log.Warn().Msgf("resource %s already has a wait option, not adding the async one", m.Resource.Name)
+		} else {
+			m.Options["wait"] = newWaitOption()
+			if _, ok := m.Returns.Returns["operation"]; !ok {
+				m.Returns.Returns["operation"] = newOperationReturn()
+			}
+		}
+	}
+
+	if m.SupportsRuntimeApiVersion() {
+		if _, ok := m.Options["api_version"]; ok {
+			log.Warn().Msgf("resource %s already has an api_version option, not adding the runtime one", m.Resource.Name)
+		} else {
+			m.Options["api_version"] = newApiVersionOption(m.ApiVersions(), apiVersionOf(m.BaseUrl(), m.Resource.MinVersion()))
+		}
+	}
+
+	if m.RecreateOnChange() || m.RequiresForceDelete() {
+		m.Options["force"] = newForceOption(m.RecreateOnChange(), m.RequiresForceDelete())
+	}
+
 	// filter the options to only include input options
 	inputOptions := make(map[string]*Option, 0)
 	for _, option := range m.Options {
@@ -52,13 +160,58 @@ func NewFromResource(resource *api.Resource) *Module {
 
 	log.Info().Msgf("creating documentation for %s", resource.AnsibleName())
 	m.Documentation = NewDocumentationFromOptions(resource, inputOptions)
+	m.Deprecated = m.Documentation.Deprecated
+	m.Documentation.SeeAlso = append(m.Documentation.SeeAlso, siblingSeeAlso(resource, config.Collection)...)
+	if !m.SupportsCheckMode {
+		m.Documentation.Attributes["check_mode"].Support = "none"
+	}
+	diffModeOverridden := resource.Overrides != nil && resource.Overrides.Attributes["diff_mode"] != ""
+	if !diffModeOverridden && len(m.LabelOptions()) > 0 {
+		m.Documentation.Attributes["diff_mode"].Support = "partial"
+		m.Documentation.Attributes["diff_mode"].Details = "Only the label changes are reported."
+	}
+
+	m.Documentation.VersionAdded = config.VersionAdded
+	if config.VersionAdded != "" {
+		m.Documentation.VersionAddedCollection = config.VersionAddedCollection
+	}
+
+	if note := m.IdempotencyNote(); note != "" {
+		m.Documentation.Notes = append(m.Documentation.Notes, note)
+	}
 
 	log.Info().Msgf("creating argument spec for %s", resource.AnsibleName())
 	m.ArgumentSpec = NewArgSpecFromOptions(inputOptions, m.Dependency)
+	m.ArgumentSpec.SkipMutuallyExclusive = len(m.MutuallyExclusiveChecks()) > 0
+	m.ArgumentSpec.SupportsCheckMode = m.SupportsCheckMode
+	m.ArgumentSpec.SortMode = config.ArgSpecSort
+
+	// the auth options are documented by the doc fragment so only the argument spec gets them
+	for name, option := range newAuthOptions() {
+		if existing, ok := m.ArgumentSpec.Arguments[name]; ok {
+			log.Warn().Msgf("option %s in %s shadows the standard auth option", name, resource.AnsibleName())
+			// e.g. the resource project still falls back to GCP_PROJECT
+			if existing.Fallback == nil {
+				existing.Fallback = option.Fallback
+			}
+			continue
+		}
+		m.ArgumentSpec.Arguments[name] = option
+	}
+	maskCredentialOptions(m.ArgumentSpec.Arguments)
 
 	return m
 }
 
+// defaultReturned returns the 'returned' condition for optional fields, the
+// resource override takes precedence over the generation config
+func defaultReturned(resource *api.Resource, config *Config) string {
+	if resource.Overrides != nil && resource.Overrides.DefaultReturned != "" {
+		return resource.Overrides.DefaultReturned
+	}
+	return config.DefaultReturned
+}
+
 func (m *Module) String() string {
 	return m.Resource.AnsibleName()
 }
@@ -68,9 +221,11 @@ func (m *Module) CustomCode() mmv1resource.CustomCode {
 	return m.Resource.Mmv1.CustomCode
 }
 
-func (m *Module) UrlParamOnlyProperties() []*mmv1api.Type {
-	return google.Select(m.Resource.Mmv1.AllUserProperties(), func(p *mmv1api.Type) bool {
-		return p.UrlParamOnly
+// UrlParamRefOptions returns the (sorted) url_param_only resource references,
+// their links are assembled before building the operation URIs
+func (m *Module) UrlParamRefOptions() []*Option {
+	return google.Select(sortedOptions(m.Options), func(o *Option) bool {
+		return o.UrlParamOnly() && o.IsResourceRef()
 	})
 }
 
@@ -85,6 +240,72 @@ func (m *Module) BaseUrl() string {
 	return ""
 }
 
+// LinkFields returns the (sorted) parameter names used to build the read and
+// delete links of the resource
+func (m *Module) LinkFields() []string {
+	fields := []string{}
+	for _, op := range []string{"read", "delete"} {
+		if config, ok := m.OperationConfigs[op]; ok {
+			for _, match := range linkFieldRegexp.FindAllStringSubmatch(config.UriTemplate, -1) {
+				if !slices.Contains(fields, match[1]) {
+					fields = append(fields, match[1])
+				}
+			}
+		}
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// QueryParams returns the query string parameters of each operation that has
+// them, keyed by operation name
+func (m *Module) QueryParams() map[string]map[string]string {
+	params := map[string]map[string]string{}
+	for name, config := range m.OperationConfigs {
+		if len(config.QueryParams) > 0 {
+			params[name] = config.QueryParams
+		}
+	}
+	return params
+}
+
+// RegionalEndpoint returns true when the product's base URL is served from a
+// per-region host e.g. https://{{region}}-aiplatform.googleapis.com/v1/
+func (m *Module) RegionalEndpoint() bool {
+	host := strings.SplitN(strings.TrimPrefix(m.BaseUrl(), "https://"), "/", 2)[0]
+	return strings.Contains(host, "{{region}}") || strings.Contains(host, "{{location}}")
+}
+
+// EndpointTemplate returns the base URL as a python format string, for
+// regional endpoints the host keeps a {region} (or {location}) placeholder so
+// it can be filled from the module options at runtime
+func (m *Module) EndpointTemplate() string {
+	return strings.ReplaceAll(strings.ReplaceAll(m.BaseUrl(), "{{", "{"), "}}", "}")
+}
+
+// ApiVersions maps the API versions (e.g. v1 and v1beta1) the resource is
+// available at to their endpoint (a python format string), the versions come
+// from the product base URLs at or above the resource min version
+func (m *Module) ApiVersions() map[string]string {
+	versions := map[string]string{}
+	minVersion := slices.Index(product.ORDER, m.Resource.MinVersion())
+	for _, version := range m.Resource.Parent.Mmv1.Versions {
+		if slices.Index(product.ORDER, version.Name) < minVersion {
+			continue
+		}
+		versions[apiVersionOf(version.BaseUrl, version.Name)] = strings.ReplaceAll(strings.ReplaceAll(version.BaseUrl, "{{", "{"), "}}", "}")
+	}
+	return versions
+}
+
+// SupportsRuntimeApiVersion returns true when the API version can be picked
+// at runtime (the api_version option), i.e. the product has several versions
+// the resource is available at
+func (m *Module) SupportsRuntimeApiVersion() bool {
+	return len(m.ApiVersions()) > 1
+}
+
 func (m *Module) ModuleClass() string {
 	return google.Camelize(m.Resource.Parent.Mmv1.Name, "upper")
 }
@@ -97,6 +318,72 @@ func (m *Module) Scopes() []string {
 	return m.Resource.Parent.Mmv1.Scopes
 }
 
+// RetryPolicy returns the policy the module's request helper uses to retry
+// transient API errors
+func (m *Module) RetryPolicy() *RetryPolicy {
+	return m.Config.Retry
+}
+
+// RequestTimeout returns the timeout (in seconds) of each API request the
+// module's request helper sends
+func (m *Module) RequestTimeout() float64 {
+	return requestTimeout(m.Config)
+}
+
+// UserAgent returns the User-Agent the module's requests identify with e.g.
+// ansible-google.cloud/1.2.0
+func (m *Module) UserAgent() string {
+	return userAgent(m.Config)
+}
+
+// ValidateOptionShapes returns the options whose type, elements and suboptions
+// disagree e.g. a list without elements, empty when all of them are consistent
+func (m *Module) ValidateOptionShapes() []string {
+	return validateOptionShapes(m.Options)
+}
+
+// SortedOperations returns the operation configs in OPERATION_ORDER, the
+// missing operations are skipped
+func (m *Module) SortedOperations() []NamedOperationConfig {
+	operations := []NamedOperationConfig{}
+	for _, name := range OPERATION_ORDER {
+		if config, ok := m.OperationConfigs[name]; ok {
+			operations = append(operations, NamedOperationConfig{Name: name, Config: config})
+		}
+	}
+	return operations
+}
+
+// Validate checks the module can be generated: every option has a type, no
+// option is both required and defaulted, choices aren't empty, required_if
+// conditions are on a sibling and the state option exists. All the problems are returned joined in a single error
+func (m *Module) Validate() error {
+	errs := validateOptions(m.Options)
+	if _, ok := m.Options["state"]; !ok {
+		errs = append(errs, fmt.Errorf("module %s has no state option", m.Name))
+	}
+	return errors.Join(errs...)
+}
+
+// IsAsync returns true when any of the resource operations is long running
+func (m *Module) IsAsync() bool {
+	async := m.GetAsync()
+	return async != nil && async.IsA(ASYNC_OPERATION) && async.Operation != nil && len(async.Actions) > 0
+}
+
+// AsyncOps returns the async style of the resource and the paths of its long
+// running operations, empty when it isn't async
+func (m *Module) AsyncOps() *AsyncOps {
+	return NewAsyncOps(m.Resource.Mmv1)
+}
+
+// PollsAfter returns true when the resource is polled after the given action
+// (create or delete) until it exists or is gone, i.e. a PollAsync resource
+func (m *Module) PollsAfter(action string) bool {
+	async := m.GetAsync()
+	return async != nil && async.IsA(ASYNC_POLL) && async.Allow(action)
+}
+
 func (m *Module) GetAsync() *mmv1api.Async {
 	return m.Resource.Mmv1.GetAsync()
 }
@@ -129,6 +416,352 @@ func (m *Module) InputOptions() []*Option {
 	})
 }
 
+// CreateBodyOptions returns the top-level options sent in the create request body
+func (m *Module) CreateBodyOptions() []*Option {
+	return google.Reject(m.InputOptions(), func(o *Option) bool {
+		return o.OutputOnly()
+	})
+}
+
+// CreateBodyFieldNames maps the API name of each create body field to its
+// option name. Check mode predicts the resource that would be created from
+// the request built at runtime, these are the fields it echoes back
+func (m *Module) CreateBodyFieldNames() map[string]string {
+	body := map[string]string{}
+	for _, option := range m.CreateBodyOptions() {
+		body[option.Name] = option.AnsibleName()
+	}
+	return body
+}
+
+// PredictedCreateBody is CreateBodyFieldNames, the fields of the resource check
+// mode predicts
+func (m *Module) PredictedCreateBody() map[string]string {
+	return m.CreateBodyFieldNames()
+}
+
+// ResponseFieldMap maps the API name of each field read back from the API to
+// its (underscored, like the options) key in the module result, restricted to
+// the returns_include override fields (if any)
+func (m *Module) ResponseFieldMap() map[string]string {
+	include := m.ReturnsInclude()
+	fields := map[string]string{}
+	for _, option := range m.OutputOptions() {
+		if len(include) > 0 && !slices.Contains(include, option.Name) && !slices.Contains(STANDARD_RETURNS, option.Name) {
+			continue
+		}
+		fields[option.Name] = option.AnsibleName()
+	}
+	return fields
+}
+
+// CheckModeSafeOptions returns the input options that can be previewed in check
+// mode without side effects, i.e. the ones that don't need a network lookup
+// to be validated
+func (m *Module) CheckModeSafeOptions() []*Option {
+	return google.Reject(sortedOptions(m.Options), func(o *Option) bool {
+		return o.OutputOnly() || o.NeedsLookup()
+	})
+}
+
+// RequiredOptionNames returns the top-level arguments that are always
+// required, sorted
+func (m *Module) RequiredOptionNames() []string {
+	names := []string{}
+	for name, option := range m.ArgumentSpec.Arguments {
+		if option.Required {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OptionChoices returns the enforced choices of the top-level arguments by name
+func (m *Module) OptionChoices() map[string][]string {
+	choices := map[string][]string{}
+	for name, option := range m.ArgumentSpec.Arguments {
+		if len(option.Choices) > 0 && option.StrictChoices() {
+			choices[name] = option.Choices
+		}
+	}
+	return choices
+}
+
+// NoLogFieldPaths returns the (dot-separated API names) paths of the request
+// and response fields holding no_log values, nested ones included, sorted
+func (m *Module) NoLogFieldPaths() []string {
+	paths := []string{}
+	var walk func(option *Option)
+	walk = func(option *Option) {
+		if option.NoLog {
+			paths = append(paths, option.Lineage())
+			return
+		}
+		for _, suboption := range sortedOptions(option.Suboptions) {
+			walk(suboption)
+		}
+	}
+	for _, option := range m.AllMmv1BodyOptions() {
+		walk(option)
+	}
+	return paths
+}
+
+// ConflictIsIdempotent returns true when a 409 (already exists) on create means
+// the resource is already present rather than an error, true unless overridden
+func (m *Module) ConflictIsIdempotent() bool {
+	if m.Resource.Overrides != nil && m.Resource.Overrides.ConflictIsIdempotent != nil {
+		return *m.Resource.Overrides.ConflictIsIdempotent
+	}
+	return true
+}
+
+// DeleteNotFoundIsOk returns true when a missing resource (or a 404 on delete)
+// with state=absent means there's nothing to do rather than an error, true
+// unless overridden
+func (m *Module) DeleteNotFoundIsOk() bool {
+	if m.Resource.Overrides != nil && m.Resource.Overrides.DeleteNotFoundIsOk != nil {
+		return *m.Resource.Overrides.DeleteNotFoundIsOk
+	}
+	return true
+}
+
+// SupportsRollback returns true when a failed create is rolled back, i.e. the
+// rollback_on_failure override is set and the resource can be deleted
+func (m *Module) SupportsRollback() bool {
+	if m.Resource.Overrides == nil || !m.Resource.Overrides.RollbackOnFailure {
+		return false
+	}
+	_, create := m.OperationConfigs["create"]
+	_, del := m.OperationConfigs["delete"]
+	return create && del
+}
+
+// ExposesRawResponse returns true when the whole API response is returned
+// under the _raw key, see the expose_raw_response override
+func (m *Module) ExposesRawResponse() bool {
+	return m.Resource.Overrides != nil && m.Resource.Overrides.ExposeRawResponse
+}
+
+// ReturnsInclude returns the (top-level API) fields the module is restricted
+// to return, empty when all of them are returned
+func (m *Module) ReturnsInclude() []string {
+	if m.Resource.Overrides == nil {
+		return []string{}
+	}
+	return m.Resource.Overrides.ReturnsInclude
+}
+
+// LabelOptions returns the top-level input options holding labels, these get
+// a dedicated before/after rendering in diff mode
+func (m *Module) LabelOptions() []*Option {
+	return google.Select(m.InputOptions(), func(o *Option) bool {
+		return o.Mmv1.Type == "KeyValueLabels"
+	})
+}
+
+// ImmutableOptions returns the input options that can't be updated in place,
+// either because the field itself or the whole resource is immutable
+func (m *Module) ImmutableOptions() []*Option {
+	return google.Select(m.InputOptions(), func(o *Option) bool {
+		return o.Mmv1.Immutable || m.Resource.Mmv1.Immutable
+	})
+}
+
+// SCOPE_PARAMS maps the location parameters that can be validated against the
+// project to the Compute Engine collection listing them
+var SCOPE_PARAMS = map[string]string{
+	"zone":   "zones",
+	"region": "regions",
+}
+
+// ValidatableScopeParams returns the (sorted) resource link parameters that
+// can be checked to exist in the project before calling the API e.g. zone
+func (m *Module) ValidatableScopeParams() []string {
+	return google.Select(m.LinkFields(), func(field string) bool {
+		_, ok := SCOPE_PARAMS[field]
+		return ok
+	})
+}
+
+// ScopeCollections returns the Compute Engine collection of each validatable
+// scope parameter, keyed by parameter
+func (m *Module) ScopeCollections() map[string]string {
+	collections := map[string]string{}
+	for _, param := range m.ValidatableScopeParams() {
+		collections[param] = SCOPE_PARAMS[param]
+	}
+	return collections
+}
+
+// PatchOnly returns the (top-level API) fields the in-place updates are
+// restricted to, empty if the whole resource can be updated
+func (m *Module) PatchOnly() []string {
+	if m.Resource.Overrides == nil {
+		return nil
+	}
+	return m.Resource.Overrides.PatchOnly
+}
+
+// UpdatableOptions returns the input options that can be updated in place,
+// restricted to the patch_only fields (if any)
+func (m *Module) UpdatableOptions() []*Option {
+	immutable := m.ImmutableOptions()
+	patchOnly := m.PatchOnly()
+	return google.Select(m.InputOptions(), func(o *Option) bool {
+		if slices.Contains(immutable, o) {
+			return false
+		}
+		return len(patchOnly) == 0 || slices.Contains(patchOnly, o.Name)
+	})
+}
+
+// UpdateMaskPaths returns the (sorted) API names of the updatable fields, as
+// sent in the updateMask of patch_only resources
+func (m *Module) UpdateMaskPaths() []string {
+	paths := []string{}
+	for _, option := range m.UpdatableOptions() {
+		paths = append(paths, option.Name)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// RecreateOnChange returns true when the module should delete and recreate the
+// resource (if forced) when an immutable field changes
+func (m *Module) RecreateOnChange() bool {
+	return m.Config.RecreateImmutable && len(m.ImmutableOptions()) > 0
+}
+
+// RequiresForceDelete returns true when deleting the resource (state=absent)
+// must be confirmed with the force option
+func (m *Module) RequiresForceDelete() bool {
+	return m.Resource.Overrides != nil && m.Resource.Overrides.DangerousDelete
+}
+
+// IdentityOptions returns (in order) all the options that make up the identity
+// of the resource, from the MMv1 identity list or the name option by default
+func (m *Module) IdentityOptions() []*Option {
+	opts := []*Option{}
+	for _, property := range m.Resource.Mmv1.GetIdentity() {
+		if option, ok := m.Options[google.Underscore(property.Name)]; ok {
+			opts = append(opts, option)
+		}
+	}
+	return opts
+}
+
+// IdentityLookupFields maps the API name of the identity fields (MMv1
+// identity) missing from the list link to their option, when the read link
+// doesn't hold the whole identity the existing resource is the listed one
+// they match. Empty when MMv1 has no identity (the name is) or the read link
+// is enough
+func (m *Module) IdentityLookupFields() map[string]string {
+	list, ok := m.OperationConfigs["list"]
+	if m.Resource.Mmv1.Identity == nil || !ok {
+		return nil
+	}
+	linkFields := m.LinkFields()
+	identity := m.IdentityOptions()
+	if !slices.ContainsFunc(identity, func(o *Option) bool { return !slices.Contains(linkFields, o.AnsibleName()) }) {
+		return nil
+	}
+	fields := map[string]string{}
+	for _, option := range identity {
+		if !strings.Contains(list.UriTemplate, "{"+option.AnsibleName()+"}") {
+			fields[option.Name] = option.AnsibleName()
+		}
+	}
+	return fields
+}
+
+// IdempotencyNote returns a documentation note naming the field(s) that
+// identify the resource and the scope (the other link parameters) they are
+// unique within, e.g. C(name) within the given C(project)/C(location)
+func (m *Module) IdempotencyNote() string {
+	config, ok := m.OperationConfigs["read"]
+	if !ok {
+		return ""
+	}
+	linkFields := []string{}
+	for _, match := range linkFieldRegexp.FindAllStringSubmatch(config.UriTemplate, -1) {
+		linkFields = append(linkFields, match[1])
+	}
+
+	identity := []string{}
+	for _, option := range m.IdentityOptions() {
+		if !option.OutputOnly() {
+			identity = append(identity, option.AnsibleName())
+		}
+	}
+	// without an input identity the last link parameter identifies the resource
+	if len(identity) == 0 && len(linkFields) > 0 {
+		identity = linkFields[len(linkFields)-1:]
+	}
+	if len(identity) == 0 {
+		return ""
+	}
+
+	quoted := func(names []string) []string {
+		q := make([]string, 0, len(names))
+		for _, name := range names {
+			q = append(q, fmt.Sprintf("C(%s)", name))
+		}
+		return q
+	}
+
+	fields := "field"
+	if len(identity) > 1 {
+		fields = "fields"
+	}
+	note := fmt.Sprintf("This module is idempotent based on the %s %s", strings.Join(quoted(identity), " and "), fields)
+
+	scope := google.Reject(linkFields, func(f string) bool {
+		return slices.Contains(identity, f)
+	})
+	if len(scope) > 0 {
+		note += fmt.Sprintf(" within the given %s", strings.Join(quoted(scope), "/"))
+	}
+
+	return note + "."
+}
+
+// MutuallyExclusiveChecks returns the top-level mutually exclusive option
+// groups the module checks itself to fail with a descriptive message, empty
+// unless enabled in the generation config
+func (m *Module) MutuallyExclusiveChecks() [][]string {
+	if !m.Config.ExplainConflicts || m.Dependency == nil {
+		return [][]string{}
+	}
+	return m.Dependency.MutuallyExclusive
+}
+
+// MutuallyExclusiveDefaults returns the python dict of the defaults of the
+// MutuallyExclusiveChecks options that have one, Ansible sets them before the
+// module checks the groups so they don't count as given
+func (m *Module) MutuallyExclusiveDefaults() string {
+	defaults := map[string]interface{}{}
+	for _, group := range m.MutuallyExclusiveChecks() {
+		for _, name := range group {
+			if option, ok := m.Options[name]; ok && option.Default != nil {
+				defaults[name] = option.Default
+			}
+		}
+	}
+	return pythonValue(defaults)
+}
+
+// MergeStrategies returns how each top-level input option (keyed by its API
+// name) is merged with the existing resource state on update
+func (m *Module) MergeStrategies() map[string]MergeStrategy {
+	strategies := map[string]MergeStrategy{}
+	for _, option := range m.InputOptions() {
+		strategies[option.Name] = option.MergeStrategy
+	}
+	return strategies
+}
+
 func (m *Module) UrlParamOnlyOptions() []*Option {
 	return google.Select(m.AllMmv1BodyOptions(), func(o *Option) bool {
 		return o.Mmv1.UrlParamOnly
@@ -146,6 +779,35 @@ func (m *Module) AllNestedOptions() map[string]*Option {
 	return nestedOptions
 }
 
+// checkClassNames makes sure every nested object gets its own python class,
+// a custom class name colliding with another class is dropped
+func (m *Module) checkClassNames() {
+	seen := map[string]*Option{}
+	var walk func(options map[string]*Option)
+	walk = func(options map[string]*Option) {
+		for _, option := range sortedOptions(options) {
+			if option.Mmv1 == nil {
+				continue
+			}
+			if option.IsNestedObject() || option.IsNestedList() {
+				name := option.ClassName()
+				if other, ok := seen[name]; (ok && other != option) || name == m.ModuleClass() {
+					if option.CustomClassName != "" {
+						log.Error().Msgf("class name %s of option %s is already in use in %s, ignoring it", name, option.Lineage(), m)
+						option.CustomClassName = ""
+						name = option.ClassName()
+					} else {
+						log.Warn().Msgf("class name %s of option %s is already in use in %s", name, option.Lineage(), m)
+					}
+				}
+				seen[name] = option
+			}
+			walk(option.Suboptions)
+		}
+	}
+	walk(m.Options)
+}
+
 // collectNestedOptions recursively collects all nested object options
 func collectNestedOptions(option *Option, result map[string]*Option) {
 	// If this option is a nested object or a list of nested objects, add it to the result
@@ -0,0 +1,846 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/thekad/magic-ansible/pkg/api"
+)
+
+const testProductYAML = `name: Widgets
+display_name: Widgets
+versions:
+  - name: ga
+    base_url: https://widgets.googleapis.com/v1/
+scopes:
+  - https://www.googleapis.com/auth/cloud-platform
+`
+
+const testResourceYAML = `name: Widget
+base_url: projects/{{project}}/locations/{{location}}/widgets
+self_link: projects/{{project}}/locations/{{location}}/widgets/{{name}}
+create_url: projects/{{project}}/locations/{{location}}/widgets?widgetId={{name}}
+parameters:
+  - name: project
+    type: String
+    description: The project.
+    url_param_only: true
+    required: true
+  - name: location
+    type: String
+    description: The location.
+    url_param_only: true
+    required: true
+  - name: name
+    type: String
+    description: The widget name.
+    url_param_only: true
+    required: true
+properties:
+  - name: displayName
+    type: String
+    description: The display name.
+    required: true
+  - name: size
+    type: String
+    description: The widget size.
+  - name: createTime
+    type: String
+    description: The creation time.
+    output: true
+`
+
+// newTestModule writes the given product.yaml and resource files (by name,
+// without the .yaml suffix) in a temporary products directory and returns the
+// module of the named resource
+func newTestModule(t *testing.T, productYAML string, resources map[string]string, name string, config *Config) *Module {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "products", "widgets")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	productFile := filepath.Join(dir, "product.yaml")
+	if err := os.WriteFile(productFile, []byte(productYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for resourceName, resourceYAML := range resources {
+		if err := os.WriteFile(filepath.Join(dir, resourceName+".yaml"), []byte(resourceYAML), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	product := api.NewProduct(productFile, t.TempDir(), t.TempDir())
+	if err := product.Unmarshal(); err != nil {
+		t.Fatal(err)
+	}
+	resource := api.NewResource(filepath.Join(dir, name+".yaml"), product, t.TempDir(), t.TempDir())
+	if err := resource.Unmarshal(); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewFromResource(resource, config)
+}
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseUrl  string
+		regional bool
+		want     string
+	}{
+		{"global", "https://widgets.googleapis.com/v1/", false, "https://widgets.googleapis.com/v1/"},
+		{"regional", "https://{{region}}-widgets.googleapis.com/v1/", true, "https://{region}-widgets.googleapis.com/v1/"},
+		{"location", "https://{{location}}-widgets.googleapis.com/v1/", true, "https://{location}-widgets.googleapis.com/v1/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := strings.Replace(testProductYAML, "https://widgets.googleapis.com/v1/", tt.baseUrl, 1)
+			m := newTestModule(t, product, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+			if got := m.RegionalEndpoint(); got != tt.regional {
+				t.Errorf("RegionalEndpoint() = %v, want %v", got, tt.regional)
+			}
+			if got := m.EndpointTemplate(); got != tt.want {
+				t.Errorf("EndpointTemplate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoolMapping(t *testing.T) {
+	resource := testResourceYAML + `  - name: logging
+    type: Enum
+    description: The logging mode.
+    enum_values:
+      - ENABLED
+      - DISABLED
+    as_bool: true
+  - name: tier
+    type: Enum
+    description: The widget tier.
+    enum_values:
+      - BASIC
+      - PREMIUM
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	logging := m.Options["logging"]
+	if logging.Type != TypeBool {
+		t.Errorf("logging type = %s, want %s", logging.Type, TypeBool)
+	}
+	if len(logging.Choices) > 0 {
+		t.Errorf("logging choices = %v, want none", logging.Choices)
+	}
+	want := BoolMapping{True: "ENABLED", False: "DISABLED"}
+	if got := logging.BoolMapping(); got == nil || *got != want {
+		t.Errorf("logging BoolMapping() = %v, want %v", got, want)
+	}
+
+	if got := m.Options["tier"].BoolMapping(); got != nil {
+		t.Errorf("tier BoolMapping() = %v, want nil", got)
+	}
+}
+
+func TestCheckModeSafeOptions(t *testing.T) {
+	resource := testResourceYAML + `  - name: gadget
+    type: ResourceRef
+    description: The gadget of the widget.
+    resource: Gadget
+    imports: name
+  - name: config
+    type: NestedObject
+    description: The widget config.
+    properties:
+      - name: peer
+        type: ResourceRef
+        description: The peer widget.
+        resource: Widget
+        imports: name
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	for _, name := range []string{"gadget", "config"} {
+		if !m.Options[name].NeedsLookup() {
+			t.Errorf("%s NeedsLookup() = false, want true", name)
+		}
+	}
+	if m.Options["display_name"].NeedsLookup() {
+		t.Errorf("display_name NeedsLookup() = true, want false")
+	}
+
+	safe := []string{}
+	for _, option := range m.CheckModeSafeOptions() {
+		safe = append(safe, option.AnsibleName())
+	}
+	for _, name := range []string{"gadget", "config", "create_time"} {
+		if slices.Contains(safe, name) {
+			t.Errorf("CheckModeSafeOptions() = %v, must not contain %s", safe, name)
+		}
+	}
+	for _, name := range []string{"display_name", "size", "project"} {
+		if !slices.Contains(safe, name) {
+			t.Errorf("CheckModeSafeOptions() = %v, must contain %s", safe, name)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	if got := m.RetryPolicy().MaxRetries; got != DEFAULT_MAX_RETRIES {
+		t.Errorf("default MaxRetries = %d, want %d", got, DEFAULT_MAX_RETRIES)
+	}
+
+	config := NewConfig()
+	config.Retry = NewRetryPolicy(5, 0.5, 3)
+	m = newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", config)
+
+	// render the retry policy line of the module template
+	contents, err := os.ReadFile(filepath.Join("..", "..", "templates", "plugins", "module.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := ""
+	for _, l := range strings.Split(string(contents), "\n") {
+		if strings.Contains(l, "gcp.RetryPolicy(") {
+			line = strings.TrimSpace(l)
+		}
+	}
+	if line == "" {
+		t.Fatal("the module template doesn't build a retry policy")
+	}
+	tmpl := template.Must(template.New("retry").Funcs(template.FuncMap{"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	}}).Parse(line))
+	rendered := strings.Builder{}
+	if err := tmpl.Execute(&rendered, m); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered.String(), `"max_retries":5`) || !strings.Contains(rendered.String(), `"multiplier":3`) {
+		t.Errorf("rendered retry policy = %s, want max_retries 5 and multiplier 3", rendered.String())
+	}
+}
+
+func TestImmutableOptions(t *testing.T) {
+	resource := strings.Replace(testResourceYAML, "    description: The widget size.\n", "    description: The widget size.\n    immutable: true\n", 1)
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	names := []string{}
+	for _, option := range m.ImmutableOptions() {
+		names = append(names, option.AnsibleName())
+	}
+	if want := []string{"size"}; !slices.Equal(names, want) {
+		t.Errorf("ImmutableOptions() = %v, want %v", names, want)
+	}
+}
+
+const testGadgetYAML = `name: Gadget
+base_url: projects/{{project}}/locations/{{location}}/gadgets
+self_link: projects/{{project}}/locations/{{location}}/gadgets/{{gadget_id}}
+properties:
+  - name: name
+    type: String
+    description: The gadget name.
+    output: true
+`
+
+func TestResourceRefSuboptions(t *testing.T) {
+	resource := testResourceYAML + `  - name: gadget
+    type: ResourceRef
+    description: The gadget of the widget.
+    resource: Gadget
+    imports: name
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource, "Gadget": testGadgetYAML}, "Widget", nil)
+
+	gadget := m.Options["gadget"]
+	if !gadget.IsResourceRef() {
+		t.Fatalf("gadget IsResourceRef() = false, want true")
+	}
+	names := []string{}
+	for name := range gadget.Suboptions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"gadget_id", "location", "project", "self_link"}; !slices.Equal(names, want) {
+		t.Errorf("gadget suboptions = %v, want %v", names, want)
+	}
+	if want := []string{"name"}; !slices.Equal(gadget.Suboptions[RESOURCE_REF_SELF_LINK].Aliases, want) {
+		t.Errorf("self_link aliases = %v, want %v", gadget.Suboptions[RESOURCE_REF_SELF_LINK].Aliases, want)
+	}
+	if want := "projects/{project}/locations/{location}/gadgets/{gadget_id}"; gadget.RefTemplate() != want {
+		t.Errorf("RefTemplate() = %s, want %s", gadget.RefTemplate(), want)
+	}
+	if want := []string{RESOURCE_REF_SELF_LINK, "gadget_id"}; len(gadget.Dependency.RequiredOneOf) != 1 || !slices.Equal(gadget.Dependency.RequiredOneOf[0], want) {
+		t.Errorf("RequiredOneOf = %v, want [%v]", gadget.Dependency.RequiredOneOf, want)
+	}
+	if want := []string{"project", "location", "gadget_id"}; len(gadget.Dependency.RequiredTogether) != 1 || !slices.Equal(gadget.Dependency.RequiredTogether[0], want) {
+		t.Errorf("RequiredTogether = %v, want [%v]", gadget.Dependency.RequiredTogether, want)
+	}
+	for _, suboption := range gadget.Suboptions {
+		if suboption.IsNestedObject() || suboption.IsNestedList() {
+			t.Errorf("suboption %s has no MMv1 type, it can't be nested", suboption.Name)
+		}
+	}
+}
+
+func TestSupportsRuntimeApiVersion(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	if m.SupportsRuntimeApiVersion() {
+		t.Errorf("SupportsRuntimeApiVersion() = true for a single version product")
+	}
+	if _, ok := m.Options["api_version"]; ok {
+		t.Errorf("api_version option added for a single version product")
+	}
+
+	product := strings.Replace(testProductYAML, "scopes:", `  - name: beta
+    base_url: https://widgets.googleapis.com/v1beta1/
+scopes:`, 1)
+	m = newTestModule(t, product, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	if !m.SupportsRuntimeApiVersion() {
+		t.Fatalf("SupportsRuntimeApiVersion() = false for a multi-version product")
+	}
+	option, ok := m.Options["api_version"]
+	if !ok {
+		t.Fatalf("no api_version option for a multi-version product")
+	}
+	if want := []string{"v1", "v1beta1"}; !slices.Equal(option.Choices, want) {
+		t.Errorf("api_version choices = %v, want %v", option.Choices, want)
+	}
+	if option.Default != "v1" {
+		t.Errorf("api_version default = %v, want v1", option.Default)
+	}
+}
+
+func TestPredictedCreateBody(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+
+	want := map[string]string{"displayName": "display_name", "size": "size"}
+	got := m.PredictedCreateBody()
+	if !maps.Equal(got, want) {
+		t.Errorf("PredictedCreateBody() = %v, want %v", got, want)
+	}
+	if !maps.Equal(m.CreateBodyFieldNames(), got) {
+		t.Errorf("CreateBodyFieldNames() = %v, want %v", m.CreateBodyFieldNames(), got)
+	}
+	required := 0
+	for _, option := range m.CreateBodyOptions() {
+		if option.Mmv1.Required {
+			required++
+			if _, ok := got[option.Name]; !ok {
+				t.Errorf("required field %s is not in the create body", option.Name)
+			}
+		}
+	}
+	if required == 0 {
+		t.Errorf("no required field in the create body")
+	}
+}
+
+func TestRequireOnPresent(t *testing.T) {
+	// stateRequirements returns the options required when state=present
+	stateRequirements := func(m *Module) []string {
+		if m.Dependency == nil {
+			return nil
+		}
+		for _, requiredIf := range m.Dependency.RequiredIf {
+			if requiredIf.Key == "state" && requiredIf.Value == "present" {
+				return requiredIf.Requirements
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		resource  string
+		heuristic bool
+		want      []string
+	}{
+		{"off by default", testResourceYAML, false, nil},
+		{"heuristic", testResourceYAML, true, []string{"display_name"}},
+		{
+			name:     "override",
+			resource: strings.Replace(testResourceYAML, "    description: The display name.\n", "    description: The display name.\n    required_on_create: true\n", 1),
+			want:     []string{"display_name"},
+		},
+		{
+			name:      "override opts out of the heuristic",
+			resource:  strings.Replace(testResourceYAML, "    description: The display name.\n", "    description: The display name.\n    required_on_create: false\n", 1),
+			heuristic: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.RequireOnPresent = tt.heuristic
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", config)
+
+			if got := stateRequirements(m); !slices.Equal(got, tt.want) {
+				t.Errorf("required_if state=present = %v, want %v", got, tt.want)
+			}
+			if got, want := m.Options["display_name"].Required, len(tt.want) == 0; got != want {
+				t.Errorf("display_name required = %v, want %v", got, want)
+			}
+			// the link fields are always required, they're needed to delete the resource
+			if !m.Options["name"].Required {
+				t.Errorf("name required = false, want true")
+			}
+		})
+	}
+}
+
+func TestRequiredIf(t *testing.T) {
+	properties := `  - name: tier
+    type: Enum
+    description: The widget tier.
+    enum_values:
+      - BASIC
+      - PREMIUM
+  - name: quota
+    type: Integer
+    description: The quota.
+    required: true
+    required_if:
+      key: %s
+      value: PREMIUM
+  - name: config
+    type: NestedObject
+    description: The configuration.
+    properties:
+      - name: kind
+        type: Enum
+        description: The kind.
+        enum_values:
+          - PREMIUM
+          - STANDARD
+      - name: size
+        type: Integer
+        description: The size.
+        required: true
+        required_if:
+          key: %s
+          value: PREMIUM
+`
+	tests := []struct {
+		name        string
+		topLevelKey string
+		nestedKey   string
+		wantErr     string
+	}{
+		{name: "siblings", topLevelKey: "tier", nestedKey: "kind"},
+		{name: "top-level key from a nested option", topLevelKey: "tier", nestedKey: "tier", wantErr: "config.size is required if tier=PREMIUM"},
+		{name: "nested key from a top-level option", topLevelKey: "config.kind", nestedKey: "kind", wantErr: "quota is required if config_kind=PREMIUM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := testResourceYAML + fmt.Sprintf(properties, tt.topLevelKey, tt.nestedKey)
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+			err := m.Validate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Validate() = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			for _, level := range []struct {
+				option     *Option
+				dependency *Dependency
+				key        string
+			}{
+				{m.Options["quota"], m.Dependency, "tier"},
+				{m.Options["config"].Suboptions["size"], m.Options["config"].Dependency, "kind"},
+			} {
+				if level.option.Required {
+					t.Errorf("%s is still required", level.option.Lineage())
+				}
+				if want := fmt.Sprintf("Required when O(%s=PREMIUM).", level.key); !slices.Contains(level.option.Description, want) {
+					t.Errorf("%s description = %v, want %q", level.option.Lineage(), level.option.Description, want)
+				}
+				if level.dependency == nil || !slices.ContainsFunc(level.dependency.RequiredIf, func(r *RequiredIf) bool {
+					return r.Key == level.key && r.Value == "PREMIUM" && slices.Equal(r.Requirements, []string{level.option.AnsibleName()})
+				}) {
+					t.Errorf("no required_if %s=PREMIUM for %s in %+v", level.key, level.option.Lineage(), level.dependency)
+				}
+			}
+		})
+	}
+}
+
+func TestBetaVersionAdded(t *testing.T) {
+	product := strings.Replace(testProductYAML, "scopes:", `  - name: beta
+    base_url: https://widgets.googleapis.com/v1beta/
+scopes:`, 1)
+	resource := testResourceYAML + `  - name: turbo
+    type: Boolean
+    description: Whether the widget is faster.
+    min_version: beta
+`
+	tests := []struct {
+		name    string
+		version string
+		// wantDocVersions is the number of version_added in the documentation,
+		// the module one included
+		wantDocVersions int
+	}{
+		{"release version", "1.2.0", 2},
+		{"unknown release", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.VersionAdded = tt.version
+			m := newTestModule(t, product, map[string]string{"Widget": resource}, "Widget", config)
+
+			turbo, ok := m.Options["turbo"]
+			if !ok {
+				t.Fatalf("no turbo option in %v", slices.Sorted(maps.Keys(m.Options)))
+			}
+			if turbo.VersionAdded != tt.version {
+				t.Errorf("turbo version_added = %q, want %q", turbo.VersionAdded, tt.version)
+			}
+			if beta := slices.Contains(turbo.Description, "Only available in the beta API."); beta != (tt.version == "") {
+				t.Errorf("turbo description = %v", turbo.Description)
+			}
+			if m.Options["display_name"].VersionAdded != "" {
+				t.Errorf("display_name version_added = %q, want none", m.Options["display_name"].VersionAdded)
+			}
+			if doc := m.Documentation.ToString(); strings.Count(doc, "version_added:") != tt.wantDocVersions {
+				t.Errorf("want %d version_added in the documentation:\n%s", tt.wantDocVersions, doc)
+			}
+		})
+	}
+}
+
+func TestVersionAddedCollection(t *testing.T) {
+	product := strings.Replace(testProductYAML, "scopes:", `  - name: beta
+    base_url: https://widgets.googleapis.com/v1beta/
+scopes:`, 1)
+	resource := testResourceYAML + `  - name: turbo
+    type: Boolean
+    description: Whether the widget is faster.
+    min_version: beta
+`
+	config := NewConfig()
+	config.VersionAdded = "2.3.0"
+	config.VersionAddedCollection = "google.cloud"
+	m := newTestModule(t, product, map[string]string{"Widget": resource}, "Widget", config)
+
+	for _, got := range []struct {
+		name       string
+		version    string
+		collection string
+	}{
+		{"module", m.Documentation.VersionAdded, m.Documentation.VersionAddedCollection},
+		{"turbo", m.Options["turbo"].VersionAdded, m.Options["turbo"].VersionAddedCollection},
+	} {
+		if got.version != "2.3.0" || got.collection != "google.cloud" {
+			t.Errorf("%s version_added = %q (%q), want 2.3.0 (google.cloud)", got.name, got.version, got.collection)
+		}
+	}
+	if option := m.Options["display_name"]; option.VersionAdded != "" || option.VersionAddedCollection != "" {
+		t.Errorf("display_name version_added = %q (%q), want none", option.VersionAdded, option.VersionAddedCollection)
+	}
+	doc := m.Documentation.ToString()
+	for _, want := range []string{"version_added: 2.3.0", "version_added_collection: google.cloud"} {
+		if got := strings.Count(doc, want); got != 2 {
+			t.Errorf("%d %q in the documentation, want 2:\n%s", got, want, doc)
+		}
+	}
+}
+
+func TestIdentityOptions(t *testing.T) {
+	regional := strings.Replace(testResourceYAML, "parameters:", "identity:\n  - region\n  - name\nparameters:", 1) + `  - name: region
+    type: String
+    description: The region.
+`
+	tests := []struct {
+		name         string
+		resource     string
+		wantOptions  []string
+		wantLookedUp map[string]string
+	}{
+		{"name by default", testResourceYAML, []string{"name"}, nil},
+		{"region and name", regional, []string{"region", "name"}, map[string]string{"region": "region", "name": "name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			got := []string{}
+			for _, option := range m.IdentityOptions() {
+				got = append(got, option.AnsibleName())
+			}
+			if !slices.Equal(got, tt.wantOptions) {
+				t.Errorf("IdentityOptions() = %v, want %v", got, tt.wantOptions)
+			}
+			if got := m.IdentityLookupFields(); !maps.Equal(got, tt.wantLookedUp) {
+				t.Errorf("IdentityLookupFields() = %v, want %v", got, tt.wantLookedUp)
+			}
+		})
+	}
+}
+
+func TestSortedOperations(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing []string
+		want    []string
+	}{
+		{"all", nil, []string{"create", "read", "update", "delete", "list"}},
+		{"no update", []string{"update"}, []string{"create", "read", "delete", "list"}},
+		{"no list", []string{"list"}, []string{"create", "read", "update", "delete"}},
+		{"no create nor list", []string{"create", "list"}, []string{"read", "update", "delete"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+			for _, name := range tt.missing {
+				delete(m.OperationConfigs, name)
+			}
+			// the configs are a map, the order must not depend on its iteration
+			for i := 0; i < 10; i++ {
+				got := []string{}
+				for _, op := range m.SortedOperations() {
+					got = append(got, op.Name)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Fatalf("SortedOperations() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCustomClassName(t *testing.T) {
+	nested := func(className string) string {
+		return testResourceYAML + `  - name: settings
+    type: NestedObject
+    description: The widget settings.
+    class_name: ` + className + `
+    properties:
+      - name: tier
+        type: String
+        description: The widget tier.
+`
+	}
+	tests := []struct {
+		name      string
+		className string
+		want      string
+	}{
+		{"custom", "WidgetSettings", "WidgetSettings"},
+		// the module class is Widgets, the derived class name is used instead
+		{"collision", "Widgets", "Settings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": nested(tt.className)}, "Widget", nil)
+			if got := m.Options["settings"].ClassName(); got != tt.want {
+				t.Errorf("ClassName() = %q, want %q", got, tt.want)
+			}
+			if option, ok := m.AllNestedOptions()[tt.want]; !ok || option != m.Options["settings"] {
+				t.Errorf("AllNestedOptions() = %v, want settings as %s", slices.Sorted(maps.Keys(m.AllNestedOptions())), tt.want)
+			}
+		})
+	}
+}
+
+func TestConflictIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     bool
+	}{
+		{"default", testResourceYAML, true},
+		{"override", "conflict_is_idempotent: false\n" + testResourceYAML, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			if got := m.ConflictIsIdempotent(); got != tt.want {
+				t.Errorf("ConflictIsIdempotent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotencyNote(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     string
+	}{
+		{"name", testResourceYAML, "This module is idempotent based on the C(name) field within the given C(project)/C(location)."},
+		{
+			name:     "identity",
+			resource: strings.Replace(testResourceYAML, "parameters:", "identity:\n  - name\n  - displayName\nparameters:", 1),
+			want:     "This module is idempotent based on the C(name) and C(display_name) fields within the given C(project)/C(location).",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			if got := m.IdempotencyNote(); got != tt.want {
+				t.Errorf("IdempotencyNote() = %q, want %q", got, tt.want)
+			}
+			if !slices.Contains(m.Documentation.Notes, tt.want) {
+				t.Errorf("the note isn't in the documentation notes %q", m.Documentation.Notes)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		version    string
+		want       string
+	}{
+		{"default collection", "", "", "ansible-" + DEFAULT_COLLECTION},
+		{"collection", "acme.cloud", "", "ansible-acme.cloud"},
+		{"collection version", "acme.cloud", "1.2.3", "ansible-acme.cloud/1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.Collection = tt.collection
+			config.CollectionVersion = tt.version
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", config)
+			if got := m.UserAgent(); got != tt.want {
+				t.Errorf("UserAgent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout float64
+		want    float64
+	}{
+		{"default", 0, DEFAULT_REQUEST_TIMEOUT},
+		{"negative", -1, DEFAULT_REQUEST_TIMEOUT},
+		{"configured", 7.5, 7.5},
+	}
+
+	if got := NewConfig().RequestTimeout; got != DEFAULT_REQUEST_TIMEOUT {
+		t.Errorf("NewConfig().RequestTimeout = %v, want %v", got, DEFAULT_REQUEST_TIMEOUT)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.RequestTimeout = tt.timeout
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", config)
+			if got := m.RequestTimeout(); got != tt.want {
+				t.Errorf("RequestTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitOption(t *testing.T) {
+	async := strings.Replace(testResourceYAML, "parameters:", `async:
+  type: OpAsync
+  actions: ['create', 'delete', 'update']
+  operation:
+    base_url: '{{op_id}}'
+parameters:`, 1)
+	tests := []struct {
+		name     string
+		resource string
+		want     bool
+	}{
+		{"async", async, true},
+		{"sync", testResourceYAML, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			wait, ok := m.Options["wait"]
+			if ok != tt.want {
+				t.Fatalf("wait option is %v, want %v", ok, tt.want)
+			}
+			if block := argumentBlock(m.ArgumentSpec.ToString(), "wait"); (block != "") != tt.want {
+				t.Errorf("wait argument is %q", block)
+			}
+			if tt.want && (wait.Type != TypeBool || wait.Default != true) {
+				t.Errorf("wait = %s (default %v), want a bool defaulting to true", wait.Type, wait.Default)
+			}
+		})
+	}
+}
+
+func TestResponseFieldMap(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+
+	want := map[string]string{"displayName": "display_name", "size": "size", "createTime": "create_time"}
+	if got := m.ResponseFieldMap(); !maps.Equal(got, want) {
+		t.Errorf("ResponseFieldMap() = %v, want %v", got, want)
+	}
+}
+
+func TestPatchOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     []string
+	}{
+		{"all", testResourceYAML, []string{"displayName", "size"}},
+		{"patch only", "patch_only:\n  - size\n" + testResourceYAML, []string{"size"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			if got := m.UpdateMaskPaths(); !slices.Equal(got, tt.want) {
+				t.Errorf("UpdateMaskPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatableScopeParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     []string
+	}{
+		{"zonal", strings.ReplaceAll(testResourceYAML, "location", "zone"), []string{"zone"}},
+		{"location", testResourceYAML, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			if got := m.ValidatableScopeParams(); !slices.Equal(got, tt.want) {
+				t.Errorf("ValidatableScopeParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
+	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
 	"github.com/rs/zerolog/log"
 )
 
@@ -15,6 +16,44 @@ type OperationConfig struct {
 	AsyncUriTemplate string `json:"async_uri"`
 	Verb             string `json:"verb"`
 	TimeoutMinutes   int    `json:"timeout_minutes"`
+
+	// QueryParams are the query string parameters of the operation URI (e.g.
+	// ?instanceId={{instance_id}}), values are python format strings
+	QueryParams map[string]string `json:"-"`
+
+	// ItemsKey is the key holding the resources in a list response, only set
+	// for the list operation
+	ItemsKey string `json:"-"`
+
+	// UpdateMask is true when the changed fields are sent in the updateMask
+	// query parameter, only set for the update operation
+	UpdateMask bool `json:"-"`
+
+	// UpdateMaskFields maps each updatable (top-level) field to its
+	// updateMask paths, the field API name unless MMv1 update_mask_fields says
+	// otherwise
+	UpdateMaskFields map[string][]string `json:"-"`
+}
+
+// OPERATION_ORDER is the order the operations of a resource are listed in
+var OPERATION_ORDER = []string{"create", "read", "update", "delete", "list"}
+
+// DEFAULT_TIMEOUT_MINUTES is the timeout of the operations MMv1 has none for,
+// the read and list operations included (same as the MMv1 default)
+const DEFAULT_TIMEOUT_MINUTES = 20
+
+// timeoutMinutes returns the given MMv1 timeout, DEFAULT_TIMEOUT_MINUTES if unset
+func timeoutMinutes(minutes int) int {
+	if minutes <= 0 {
+		return DEFAULT_TIMEOUT_MINUTES
+	}
+	return minutes
+}
+
+// NamedOperationConfig is an operation config along with its operation name
+type NamedOperationConfig struct {
+	Name   string
+	Config *OperationConfig
 }
 
 func NewOperationConfigsFromMmv1(mmv1 *mmv1api.Resource) map[string]*OperationConfig {
@@ -39,32 +78,70 @@ func NewOperationConfigsFromMmv1(mmv1 *mmv1api.Resource) map[string]*OperationCo
 		return strings.ReplaceAll(strings.ReplaceAll(s, "{{", "{"), "}}", "}")
 	}
 
+	// splitQuery separates the query string parameters from the given URI
+	splitQuery := func(uri string) (string, map[string]string) {
+		path, query, found := strings.Cut(uri, "?")
+		if !found {
+			return uri, nil
+		}
+		params := map[string]string{}
+		for _, pair := range strings.Split(query, "&") {
+			if pair == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(pair, "=")
+			params[key] = value
+		}
+		return path, params
+	}
+
 	ops["read"] = &OperationConfig{
 		UriTemplate:      escapeCurlyBraces(mmv1.SelfLinkUri()),
 		Verb:             getVerb(mmv1.ReadVerb, "read"),
+		TimeoutMinutes:   DEFAULT_TIMEOUT_MINUTES,
 		AsyncUriTemplate: "",
 	}
 	ops["create"] = &OperationConfig{
 		UriTemplate:      escapeCurlyBraces(mmv1.CreateUri()),
 		Verb:             getVerb(mmv1.CreateVerb, "create"),
-		TimeoutMinutes:   timeouts.InsertMinutes,
+		TimeoutMinutes:   timeoutMinutes(timeouts.InsertMinutes),
 		AsyncUriTemplate: "",
 	}
 	ops["update"] = &OperationConfig{
 		UriTemplate:      escapeCurlyBraces(mmv1.UpdateUri()),
 		Verb:             getVerb(mmv1.UpdateVerb, "update"),
-		TimeoutMinutes:   timeouts.UpdateMinutes,
+		TimeoutMinutes:   timeoutMinutes(timeouts.UpdateMinutes),
 		AsyncUriTemplate: "",
+		UpdateMask:       mmv1.UpdateMask,
+		UpdateMaskFields: updateMaskFields(mmv1),
 	}
 	ops["delete"] = &OperationConfig{
 		UriTemplate:      escapeCurlyBraces(mmv1.DeleteUri()),
 		Verb:             getVerb(mmv1.DeleteVerb, "delete"),
-		TimeoutMinutes:   timeouts.DeleteMinutes,
+		TimeoutMinutes:   timeoutMinutes(timeouts.DeleteMinutes),
 		AsyncUriTemplate: "",
 	}
 
+	// query parameters are rendered separately so their values get URL encoded
+	for _, op := range ops {
+		op.UriTemplate, op.QueryParams = splitQuery(op.UriTemplate)
+	}
+
+	// the collection is listed to find resources by name when the self link
+	// has a server-assigned ID, the (create) query parameters don't apply
+	listUri, _ := splitQuery(escapeCurlyBraces(mmv1.BaseUrl))
+	ops["list"] = &OperationConfig{
+		UriTemplate:      listUri,
+		Verb:             "GET",
+		TimeoutMinutes:   DEFAULT_TIMEOUT_MINUTES,
+		AsyncUriTemplate: "",
+		ItemsKey:         listItemsKey(mmv1),
+	}
+
+	// only the long running operations are polled through their own URI,
+	// PollAsync resources are polled through the read operation
 	async := mmv1.GetAsync()
-	if async != nil {
+	if async != nil && async.IsA(ASYNC_OPERATION) && async.Operation != nil {
 		for _, action := range async.Actions {
 			ops[strings.ToLower(action)].AsyncUriTemplate = escapeCurlyBraces(async.Operation.BaseUrl)
 		}
@@ -74,3 +151,36 @@ func NewOperationConfigsFromMmv1(mmv1 *mmv1api.Resource) map[string]*OperationCo
 
 	return ops
 }
+
+// updateMaskFields maps the updatable fields (neither output-only nor
+// immutable) of the resource to their updateMask paths
+func updateMaskFields(mmv1 *mmv1api.Resource) map[string][]string {
+	fields := map[string][]string{}
+	if mmv1.Immutable {
+		return fields
+	}
+	for _, property := range mmv1.AllUserProperties() {
+		if property.Output || property.Immutable || property.UrlParamOnly {
+			continue
+		}
+		switch {
+		case len(property.UpdateMaskFields) > 0:
+			fields[property.Name] = property.UpdateMaskFields
+		case property.ApiName != "":
+			fields[property.Name] = []string{property.ApiName}
+		default:
+			fields[property.Name] = []string{property.Name}
+		}
+	}
+	return fields
+}
+
+// listItemsKey returns the key holding the resources in a list response, the
+// nested query key or collection_url_key if set, the camelized plural resource
+// name otherwise (same default as MMv1)
+func listItemsKey(mmv1 *mmv1api.Resource) string {
+	if key := mmv1.ResourceListKey(); key != "" {
+		return key
+	}
+	return google.Camelize(google.Plural(mmv1.Name), "lower")
+}
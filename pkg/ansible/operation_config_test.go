@@ -0,0 +1,44 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestListItemsKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     string
+	}{
+		{"default", testResourceYAML, "widgets"},
+		{"collection_url_key", "collection_url_key: resources\n" + testResourceYAML, "resources"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			if got := m.ListItemsKey(); got != tt.want {
+				t.Errorf("ListItemsKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperationQueryParams(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+
+	create := m.OperationConfigs["create"]
+	if want := "projects/{project}/locations/{location}/widgets"; create.UriTemplate != want {
+		t.Errorf("create uri = %q, want %q", create.UriTemplate, want)
+	}
+	if want := map[string]string{"widgetId": "{name}"}; !maps.Equal(create.QueryParams, want) {
+		t.Errorf("create query params = %v, want %v", create.QueryParams, want)
+	}
+	if params := m.QueryParams(); len(params) != 1 || params["create"] == nil {
+		t.Errorf("QueryParams() = %v, want the create ones only", params)
+	}
+}
@@ -4,12 +4,16 @@
 package ansible
 
 import (
+	"fmt"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
 	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
 	"github.com/rs/zerolog/log"
+	"github.com/thekad/magic-ansible/pkg/api"
 )
 
 // Type represents the data types supported by Ansible modules
@@ -47,6 +51,11 @@ func MapMmv1ToAnsible(property *mmv1api.Type) Type {
 		return TypeStr
 	case "Integer":
 		return TypeInt
+	case "Double":
+		return TypeFloat
+	case "Time":
+		// RFC3339 timestamps and durations are passed through as strings
+		return TypeStr
 	case "Boolean":
 		return TypeBool
 	case "NestedObject":
@@ -71,12 +80,48 @@ func MapMmv1ToAnsible(property *mmv1api.Type) Type {
 	}
 }
 
+// MergeStrategy represents how user input is merged with the existing state
+// of the resource when updating it
+type MergeStrategy string
+
+const (
+	// MergeReplace replaces the existing value with the user input
+	MergeReplace MergeStrategy = "replace"
+	// MergeMerge merges the user input keys into the existing dictionary
+	MergeMerge MergeStrategy = "merge"
+	// MergeAppend appends the user input items to the existing list
+	MergeAppend MergeStrategy = "append"
+)
+
+// defaultMergeStrategy returns the merge strategy for the given option type,
+// dictionaries (labels included) are merged and everything else is replaced
+func defaultMergeStrategy(option *Option) MergeStrategy {
+	if option.Type == TypeDict {
+		return MergeMerge
+	}
+	return MergeReplace
+}
+
 type Dependency struct {
 	// MutuallyExclusive is optional - list of options that cannot be used together
 	MutuallyExclusive [][]string `yaml:"mutually_exclusive,omitempty"`
 
 	// RequiredTogether is optional - list of options that must be used together
 	RequiredTogether [][]string `yaml:"required_together,omitempty"`
+
+	// RequiredOneOf is optional - list of options of which at least one is required
+	RequiredOneOf [][]string `yaml:"required_one_of,omitempty"`
+
+	// RequiredIf is optional - list of options required when another option has a given value
+	RequiredIf []*RequiredIf `yaml:"required_if,omitempty"`
+}
+
+// RequiredIf is a conditional requirement: when the option Key is set to
+// Value, all the Requirements must be set too
+type RequiredIf struct {
+	Key          string
+	Value        interface{}
+	Requirements []string
 }
 
 // Option represents a single option in the Ansible module documentation
@@ -102,11 +147,22 @@ type Option struct {
 	// Default is optional - default value for the option
 	Default interface{} `yaml:"default,omitempty"`
 
+	// VersionAdded is optional - the collection version the option became
+	// available in, only set on the options the beta API alone has
+	VersionAdded string `yaml:"version_added,omitempty"`
+
+	// VersionAddedCollection is optional - the collection VersionAdded refers to
+	VersionAddedCollection string `yaml:"version_added_collection,omitempty"`
+
+	// Aliases is optional - alternate names accepted for this option
+	Aliases []string `yaml:"aliases,omitempty"`
+
 	// Required is optional - whether this option is required
 	// Defaults to false if not specified
 	Required bool `yaml:"required,omitempty"`
 
-	// Choices is optional - list of valid values for this option
+	// Choices is optional - list of valid values for this option, for lists
+	// (type=list) the valid values of each element
 	Choices []string `yaml:"choices,omitempty"`
 
 	// Elements is optional - if type='list', specifies the data type of list elements
@@ -121,6 +177,12 @@ type Option struct {
 	// RequiredWith is optional - list of options that must be used together with this option
 	RequiredWith []string `yaml:"-"`
 
+	// ExactlyOneOf is optional - list of options (this one included) of which exactly one must be set
+	ExactlyOneOf []string `yaml:"-"`
+
+	// AtLeastOneOf is optional - list of options (this one included) of which at least one must be set
+	AtLeastOneOf []string `yaml:"-"`
+
 	// NoLog is optional - whether this option is sensitive and should not be logged
 	NoLog bool `yaml:"-"`
 
@@ -129,6 +191,124 @@ type Option struct {
 
 	// Dependency is optional - dependency constraints for this option
 	Dependency *Dependency `yaml:"-"`
+
+	// BoolEnum is optional - the API enum values a bool option maps to
+	BoolEnum *BoolMapping `yaml:"-"`
+
+	// Fallback is optional - where to look up the value when it's not set
+	Fallback *Fallback `yaml:"-"`
+
+	// LooseChoices is optional - document the choices without enforcing them
+	LooseChoices bool `yaml:"-"`
+
+	// MergeStrategy is optional - how the input is merged with the existing state
+	MergeStrategy MergeStrategy `yaml:"-"`
+
+	// CustomClassName is optional - overrides the derived python class name
+	CustomClassName string `yaml:"-"`
+
+	// RequiredIf is optional - condition (on a sibling option) making this option required
+	RequiredIf *RequiredIf `yaml:"-"`
+
+	// explicitNoLog is true when NoLog was set by an override
+	explicitNoLog bool
+
+	// encoding is how the value is encoded in the API e.g. base64
+	encoding string
+
+	// refTemplate is the python format string a ResourceRef is assembled from
+	// when given as components e.g. projects/{project}/locations/{location}/clusters/{cluster_id}
+	refTemplate string
+}
+
+// Fallback represents the argument spec 'fallback' of an option, currently
+// only env_fallback is supported
+type Fallback struct {
+	// EnvVars are the environment variables checked (in order) by env_fallback
+	EnvVars []string
+}
+
+// NewEnvFallback is a constructor that returns a Fallback reading the value
+// from the given environment variables
+func NewEnvFallback(envVars ...string) *Fallback {
+	return &Fallback{EnvVars: envVars}
+}
+
+// BoolMapping holds the API enum values sent for a bool option that wraps a
+// two-valued enum (e.g. true -> ENABLED, false -> DISABLED)
+type BoolMapping struct {
+	True  string `json:"true"`
+	False string `json:"false"`
+}
+
+// truthy/falsy enum values recognized when retyping an enum as a bool
+var (
+	truthyEnumValues = []string{"TRUE", "ENABLED", "ENABLE", "ON", "YES"}
+	falsyEnumValues  = []string{"FALSE", "DISABLED", "DISABLE", "OFF", "NO"}
+)
+
+// newBoolMapping finds the enum values that map to true/false, returns nil
+// if the enum values can't be mapped to a boolean
+func newBoolMapping(enumValues []string) *BoolMapping {
+	bm := &BoolMapping{}
+	for _, value := range enumValues {
+		if slices.Contains(truthyEnumValues, strings.ToUpper(value)) {
+			bm.True = value
+		}
+		if slices.Contains(falsyEnumValues, strings.ToUpper(value)) {
+			bm.False = value
+		}
+	}
+	if bm.True == "" || bm.False == "" {
+		return nil
+	}
+	return bm
+}
+
+// BoolMapping returns the true/false to API enum mapping for options that
+// were retyped from an enum to a bool, nil otherwise
+func (o *Option) BoolMapping() *BoolMapping {
+	return o.BoolEnum
+}
+
+// SUPPORTED_ENCODINGS are the values of the encoding property override, each
+// one has a <encoding>_encode and <encoding>_decode module_utils helper
+var SUPPORTED_ENCODINGS = []string{"base64"}
+
+// Encoding returns how the value is encoded in the API (e.g. base64), empty
+// when it is sent as-is
+func (o *Option) Encoding() string {
+	return o.encoding
+}
+
+// RefTemplate returns the python format string the resource reference is
+// assembled from when given as components, empty if this is not a ResourceRef
+func (o *Option) RefTemplate() string {
+	return o.refTemplate
+}
+
+// RefKey returns the key holding the link of a returned resource reference,
+// empty if this is not a ResourceRef
+func (o *Option) RefKey() string {
+	if !o.IsResourceRef() {
+		return ""
+	}
+	return resourceRefKey(o.Mmv1)
+}
+
+// StrictChoices returns true when the choices must be enforced by the argument
+// spec, false when they are only documented and the API validates the value
+func (o *Option) StrictChoices() bool {
+	return !o.LooseChoices
+}
+
+// Lineage returns the dot-separated MMv1 property names from the top-level
+// option down to this one e.g. networkConfig.network
+func (o *Option) Lineage() string {
+	if o.Parent != nil {
+		return o.Parent.Lineage() + "." + o.Name
+	}
+	return o.Name
 }
 
 func (o *Option) OutputOnly() bool {
@@ -180,12 +360,39 @@ func (o *Option) InputSuboptions() []*Option {
 	})
 }
 
+// NeedsLookup returns true when validating this option requires a network
+// call e.g. a ResourceRef that has to be resolved against the referenced resource
+func (o *Option) NeedsLookup() bool {
+	if o.Mmv1 != nil {
+		if o.Mmv1.IsA("ResourceRef") {
+			return true
+		}
+		if o.Mmv1.ItemType != nil && o.Mmv1.ItemType.IsA("ResourceRef") {
+			return true
+		}
+	}
+
+	for _, suboption := range o.Suboptions {
+		if suboption.NeedsLookup() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (o *Option) IsList() bool {
 	return o.Type == TypeList
 }
 
 func (o *Option) IsNestedObject() bool {
-	return o.Mmv1.IsA("NestedObject")
+	return o.Mmv1 != nil && o.Mmv1.IsA("NestedObject")
+}
+
+// IsResourceRef returns true when the option is a reference to another
+// resource, given either as a self link or as its components
+func (o *Option) IsResourceRef() bool {
+	return o.Mmv1 != nil && o.Mmv1.IsA("ResourceRef") && o.Type == TypeDict
 }
 
 func (o *Option) IsNestedList() bool {
@@ -196,7 +403,16 @@ func (o *Option) AnsibleName() string {
 	return google.Underscore(o.Name)
 }
 
+// PyVarName returns the name of the python local variable holding this option,
+// which differs from the option name when it would shadow a builtin e.g. type_
+func (o *Option) PyVarName() string {
+	return pythonVarName(o.AnsibleName())
+}
+
 func (o *Option) ClassName() string {
+	if o.CustomClassName != "" {
+		return o.CustomClassName
+	}
 	if o.IsNestedList() {
 		if o.Parent != nil {
 			return o.Parent.ClassName() + google.Camelize(strings.TrimSuffix(o.Name, "s"), "upper")
@@ -212,36 +428,107 @@ func (o *Option) ClassName() string {
 }
 
 func (o *Option) ElementsAre(q string) bool {
-	return o.Mmv1.ItemType.IsA(q)
+	return o.Mmv1 != nil && o.Mmv1.ItemType != nil && o.Mmv1.ItemType.IsA(q)
 }
 
 // NewOptionsFromMmv1 creates a map of Ansible options from a magic-modules API Resource
 // This constructor extracts user properties from the API Resource and converts them
 // to Ansible module options following the documentation format
-func NewOptionsFromMmv1(resource *mmv1api.Resource) map[string]*Option {
+func NewOptionsFromMmv1(resource *mmv1api.Resource, overrides api.PropertyOverridesMap) map[string]*Option {
 	if resource == nil {
 		return nil
 	}
 
 	// Process all user properties from the API Resource
-	options := convertPropertiesToOptions(resource.AllUserProperties(), nil)
+	options := convertPropertiesToOptions(resource.AllUserProperties(), nil, overrides)
 
 	// Always add the standard 'state' option for GCP resources
-	options["state"] = &Option{
+	options["state"] = newStateOption(DEFAULT_STATE)
+
+	return options
+}
+
+// DEFAULT_STATE is the default of the state option unless overridden
+const DEFAULT_STATE = "present"
+
+// newStateOption returns the standard 'state' option with the given default
+func newStateOption(defaultState string) *Option {
+	return &Option{
 		Name: "state",
 		Description: []string{
 			"Whether the resource should exist in GCP.",
 		},
 		Type:    TypeStr,
-		Default: "present",
+		Default: defaultState,
 		Choices: []string{"present", "absent"},
 	}
+}
 
-	return options
+// newForceOption returns the standard 'force' option used to confirm
+// destructive operations e.g. recreating or deleting a resource
+func newForceOption(recreate bool, delete bool) *Option {
+	description := []string{"Confirm destructive operations on the resource."}
+	if recreate {
+		description = append(description, "When an immutable field changes, the resource is deleted and created again if this is set to C(true).")
+	}
+	if delete {
+		description = append(description, "The resource is only deleted with O(state=absent) if this is set to C(true).")
+	}
+	return &Option{
+		Name:        "force",
+		Description: description,
+		Type:        TypeBool,
+		Default:     false,
+	}
+}
+
+// newWaitOption returns the 'wait' option of async resources, to skip polling
+// the long running operations
+func newWaitOption() *Option {
+	return &Option{
+		Name: "wait",
+		Description: []string{
+			"Wait for the long running operation to finish.",
+			"When set to C(false), the module returns as soon as the operation is submitted, with its name in RV(operation).",
+		},
+		Type:    TypeBool,
+		Default: true,
+	}
+}
+
+// newApiVersionOption returns the 'api_version' option of the resources
+// available at several API versions, to pick the endpoint at runtime
+func newApiVersionOption(versions map[string]string, defaultVersion string) *Option {
+	choices := make([]string, 0, len(versions))
+	for version := range versions {
+		choices = append(choices, version)
+	}
+	sort.Strings(choices)
+	return &Option{
+		Name: "api_version",
+		Description: []string{
+			"The version of the API the requests are sent to.",
+			"Fields missing from the chosen version are rejected by the API.",
+		},
+		Type:    TypeStr,
+		Default: defaultVersion,
+		Choices: choices,
+	}
+}
+
+// apiVersionOf returns the API version of the given base URL, its last path
+// element e.g. v1beta1 for https://redis.googleapis.com/v1beta1/, or the
+// given fallback (the MMv1 version name) if there's none
+func apiVersionOf(baseUrl string, fallback string) string {
+	elements := strings.Split(strings.TrimRight(baseUrl, "/"), "/")
+	if version := elements[len(elements)-1]; len(elements) > 3 && version != "" {
+		return version
+	}
+	return fallback
 }
 
 // convertPropertiesToOptions converts MMv1 properties to Ansible options
-func convertPropertiesToOptions(properties []*mmv1api.Type, parent *Option) map[string]*Option {
+func convertPropertiesToOptions(properties []*mmv1api.Type, parent *Option, overrides api.PropertyOverridesMap) map[string]*Option {
 	if properties == nil {
 		return nil
 	}
@@ -249,6 +536,7 @@ func convertPropertiesToOptions(properties []*mmv1api.Type, parent *Option) map[
 	options := map[string]*Option{}
 
 	for _, property := range properties {
+		optionType := MapMmv1ToAnsible(property)
 
 		// Create the option
 		option := &Option{
@@ -256,31 +544,96 @@ func convertPropertiesToOptions(properties []*mmv1api.Type, parent *Option) map[
 			Mmv1:         property,
 			Parent:       parent,
 			Description:  parsePropertyDescription(property),
-			Type:         MapMmv1ToAnsible(property),
+			Type:         optionType,
 			Required:     property.Required,
-			Default:      property.DefaultValue,
+			Default:      normalizeDefault(property.DefaultValue, optionType),
 			Choices:      property.EnumValues,
 			Conflicts:    property.Conflicts,
 			RequiredWith: property.RequiredWith,
+			ExactlyOneOf: property.ExactlyOneOf,
+			AtLeastOneOf: property.AtLeastOneOf,
 			NoLog:        property.Sensitive,
 			Output:       property.Output,
 		}
 
 		// log.Debug().Msgf("converted property %s (parent: %v, class name: %s)", property.Name, parent, option.ClassName())
 
+		// for lists of enums the choices apply to each element
+		if property.IsA("Array") && property.ItemType != nil && property.ItemType.IsA("Enum") {
+			option.Choices = property.ItemType.EnumValues
+		}
+
+		if overrides.Get(option.Lineage()).AsBool {
+			applyBoolMapping(option)
+		}
+
+		if value := overrides.Get(option.Lineage()).Default; value != nil {
+			option.Default = normalizeDefault(value, option.Type)
+			// an option with a default can't be required
+			option.Required = false
+		}
+
+		if description := overrides.Get(option.Lineage()).Description(); len(description) > 0 {
+			option.Description = description
+		}
+
+		if noLog := overrides.Get(option.Lineage()).NoLog; noLog != nil {
+			option.NoLog = *noLog
+			option.explicitNoLog = true
+		}
+
+		option.CustomClassName = overrides.Get(option.Lineage()).ClassName
+		option.Aliases = overrides.Get(option.Lineage()).Aliases
+		if requiredIf := overrides.Get(option.Lineage()).RequiredIf; requiredIf != nil {
+			// only relaxed (and documented) once the constraint is emitted, see getDependency
+			option.RequiredIf = &RequiredIf{Key: google.Underscore(requiredIf.Key), Value: requiredIf.Value}
+		}
+
+		option.MergeStrategy = defaultMergeStrategy(option)
+		if strategy := MergeStrategy(overrides.Get(option.Lineage()).MergeStrategy); strategy != "" {
+			switch strategy {
+			case MergeReplace, MergeMerge, MergeAppend:
+				option.MergeStrategy = strategy
+			default:
+				log.Warn().Msgf("unknown merge strategy '%s' for option %s, using %s", strategy, option.Lineage(), option.MergeStrategy)
+			}
+		}
+
+		if strict := overrides.Get(option.Lineage()).StrictChoices; strict != nil && !*strict && len(option.Choices) > 0 {
+			option.LooseChoices = true
+			option.Description = append(option.Description, "Values not listed in the choices are passed through to the API, which validates them.")
+		}
+
+		if encoding := overrides.Get(option.Lineage()).Encoding; encoding != "" {
+			switch {
+			case !slices.Contains(SUPPORTED_ENCODINGS, encoding):
+				log.Warn().Msgf("unsupported encoding '%s' for option %s, expected one of %v", encoding, option.Lineage(), SUPPORTED_ENCODINGS)
+			case option.Type != TypeStr:
+				log.Warn().Msgf("option %s is a %s, only strings can have an encoding", option.Lineage(), option.Type)
+			default:
+				option.encoding = encoding
+				option.Description = append(option.Description, fmt.Sprintf("Given as plain text, the value is %s-encoded before it is sent to the API (and decoded when read).", encoding))
+			}
+		}
+
 		// Handle list element types
 		if option.Type == TypeList && property.ItemType != nil {
 			option.Elements = MapMmv1ToAnsible(property.ItemType)
 
 			// If the list contains nested objects, create suboptions for the element type
 			if property.ItemType.Type == "NestedObject" && property.ItemType.Properties != nil {
-				option.Suboptions = convertPropertiesToOptions(property.ItemType.Properties, option)
+				option.Suboptions = convertPropertiesToOptions(property.ItemType.Properties, option, overrides)
+				// constraints between suboptions apply to each element of the list
+				option.Dependency = getDependency(option.Suboptions)
+				if option.Dependency != nil {
+					log.Debug().Msgf("option %s has dependency in its element suboptions: %+v", option.Name, option.Dependency)
+				}
 			}
 		}
 
 		// Handle nested dictionary objects (direct suboptions)
 		if option.Type == TypeDict && property.Properties != nil {
-			option.Suboptions = convertPropertiesToOptions(property.Properties, option)
+			option.Suboptions = convertPropertiesToOptions(property.Properties, option, overrides)
 			option.Dependency = getDependency(option.Suboptions)
 			if option.Dependency != nil {
 				log.Debug().Msgf("option %s has dependency in its suboptions: %+v", option.Name, option.Dependency)
@@ -293,6 +646,338 @@ func convertPropertiesToOptions(properties []*mmv1api.Type, parent *Option) map[
 	return options
 }
 
+// normalizeDefault coerces a default value to the given option type, so it's
+// rendered the same way in the documentation (true, 10) and the argument spec
+// (True, 10). Values that can't be coerced are returned untouched
+func normalizeDefault(value interface{}, t Type) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	switch t {
+	case TypeBool:
+		if v, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+	case TypeInt:
+		switch v := value.(type) {
+		case string:
+			if i, err := strconv.Atoi(v); err == nil {
+				return i
+			}
+		case float64:
+			if v == float64(int(v)) {
+				return int(v)
+			}
+		}
+	case TypeFloat:
+		switch v := value.(type) {
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		case int:
+			return float64(v)
+		}
+	case TypeStr:
+		// e.g. a "true" string default must not become a bool
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+
+	return value
+}
+
+// applyBoolMapping retypes an enum option as a bool, documenting which API
+// value each boolean is sent as
+func applyBoolMapping(option *Option) {
+	bm := newBoolMapping(option.Choices)
+	if bm == nil {
+		log.Warn().Msgf("option %s can't be converted to bool, enum values %v are not boolean-like", option.Lineage(), option.Choices)
+		return
+	}
+
+	option.BoolEnum = bm
+	option.Type = TypeBool
+	option.Choices = nil
+	if option.Default != nil {
+		option.Default = fmt.Sprintf("%v", option.Default) == bm.True
+	}
+	option.Description = append(option.Description, fmt.Sprintf("C(true) is sent to the API as C(%s) and C(false) as C(%s).", bm.True, bm.False))
+}
+
+// requireOnPresent relaxes the required options with the required_on_create
+// override into a required_if on state=present. With the heuristic, the
+// required options that aren't part of the resource link (i.e. not needed to
+// read or delete it) are relaxed too unless the override is false. Returns the
+// relaxed option names, sorted
+func requireOnPresent(options map[string]*Option, linkFields []string, overrides api.PropertyOverridesMap, heuristic bool) []string {
+	relaxed := []string{}
+	for name, option := range options {
+		if !option.Required || option.Mmv1 == nil {
+			continue
+		}
+		onCreate := heuristic && !slices.Contains(linkFields, name)
+		if override := overrides.Get(option.Lineage()).RequiredOnCreate; override != nil {
+			onCreate = *override
+		}
+		if !onCreate {
+			continue
+		}
+		option.Required = false
+		option.Description = append(option.Description, "Required when O(state=present).")
+		relaxed = append(relaxed, name)
+	}
+	sort.Strings(relaxed)
+
+	return relaxed
+}
+
+// addParentContext recursively prefixes the description of suboptions whose
+// original description is shorter than minLength with the parent option path
+// e.g. "For settings.ip_configuration: The value."
+func addParentContext(options map[string]*Option, parentPath string, minLength int) {
+	for _, option := range options {
+		path := option.AnsibleName()
+		if parentPath != "" {
+			path = parentPath + "." + path
+			if option.Mmv1 != nil && len(strings.TrimSpace(option.Mmv1.Description)) < minLength && len(option.Description) > 0 {
+				option.Description[0] = fmt.Sprintf("For %s: %s", parentPath, option.Description[0])
+			}
+		}
+		addParentContext(option.Suboptions, path, minLength)
+	}
+}
+
+// RESOURCE_REF_SELF_LINK is the suboption holding the full link of a resource
+// reference, it takes precedence over the component suboptions
+const RESOURCE_REF_SELF_LINK = "self_link"
+
+// resourceRefKey returns the key a resource reference is returned under: the
+// key imported from the referenced resource (also accepted as an alias of
+// self_link, so the returned reference can be given back as-is) or self_link
+func resourceRefKey(property *mmv1api.Type) string {
+	if imports := google.Underscore(string(property.Imports)); imports != "" {
+		return imports
+	}
+	return RESOURCE_REF_SELF_LINK
+}
+
+// addResourceRefComponents recursively gives the ResourceRef options a
+// self_link suboption plus one suboption per parameter of the referenced
+// resource self link (e.g. project, location and cluster_id), required
+// together unless self_link is used. The key
+// imported from the registered output of the referenced resource is accepted
+// as an alias of self_link
+func addResourceRefComponents(options map[string]*Option, product *api.Product) {
+	for _, option := range options {
+		addResourceRefComponents(option.Suboptions, product)
+		if !option.IsResourceRef() {
+			continue
+		}
+
+		resource := option.Mmv1.Resource
+		imports := google.Underscore(string(option.Mmv1.Imports))
+		template := ""
+		if product != nil {
+			template = strings.ReplaceAll(strings.ReplaceAll(product.ResourceSelfLink(resource), "{{", "{"), "}}", "}")
+			template = strings.ReplaceAll(template, "{%", "{")
+		}
+		components := []string{}
+		for _, match := range linkFieldRegexp.FindAllStringSubmatch(template, -1) {
+			if !slices.Contains(components, match[1]) {
+				components = append(components, match[1])
+			}
+		}
+		if len(components) == 0 {
+			log.Warn().Msgf("cannot find the self link of %s referenced by %s, only %s is accepted", resource, option.Name, RESOURCE_REF_SELF_LINK)
+			template = ""
+		}
+
+		selfLink := &Option{
+			Name:        RESOURCE_REF_SELF_LINK,
+			Parent:      option,
+			Description: []string{fmt.Sprintf("The full resource name (self link) of the %s, used as-is when set.", resource)},
+			Type:        TypeStr,
+		}
+		if imports != "" && imports != RESOURCE_REF_SELF_LINK && !slices.Contains(components, imports) {
+			selfLink.Aliases = []string{imports}
+		}
+		option.Suboptions = map[string]*Option{RESOURCE_REF_SELF_LINK: selfLink}
+		for _, component := range components {
+			option.Suboptions[component] = &Option{
+				Name:        component,
+				Parent:      option,
+				Description: []string{fmt.Sprintf("The %s of the %s.", component, resource)},
+				Type:        TypeStr,
+			}
+		}
+		option.refTemplate = template
+
+		required := []string{RESOURCE_REF_SELF_LINK}
+		if len(components) > 0 {
+			required = append(required, components[len(components)-1])
+		}
+		if option.Dependency == nil {
+			option.Dependency = &Dependency{}
+		}
+		option.Dependency.RequiredOneOf = append(option.Dependency.RequiredOneOf, required)
+		if len(components) > 1 {
+			option.Dependency.RequiredTogether = append(option.Dependency.RequiredTogether, components)
+		}
+	}
+}
+
+// validateOptionShapes recursively checks the type, elements and suboptions of
+// the given options agree with each other
+func validateOptionShapes(options map[string]*Option) []string {
+	problems := []string{}
+	for _, option := range sortedOptions(options) {
+		switch {
+		case option.Type == TypeList && option.Elements == "":
+			problems = append(problems, fmt.Sprintf("%s is a list without elements", option.Lineage()))
+		case option.Type != TypeList && option.Elements != "":
+			problems = append(problems, fmt.Sprintf("%s is a %s with elements %s", option.Lineage(), option.Type, option.Elements))
+		}
+		if len(option.Suboptions) > 0 {
+			switch {
+			case option.Type == TypeList && option.Elements != TypeDict:
+				problems = append(problems, fmt.Sprintf("%s has suboptions but its elements are %s", option.Lineage(), option.Elements))
+			case option.Type != TypeList && option.Type != TypeDict:
+				problems = append(problems, fmt.Sprintf("%s has suboptions but it is a %s", option.Lineage(), option.Type))
+			}
+		}
+		problems = append(problems, validateOptionShapes(option.Suboptions)...)
+	}
+
+	return problems
+}
+
+// validateOptions recursively checks the given options would generate a valid
+// argument spec and documentation, returning every problem found
+func validateOptions(options map[string]*Option) []error {
+	errs := []error{}
+	for _, option := range sortedOptions(options) {
+		if option.Type == "" {
+			errs = append(errs, fmt.Errorf("option %s has no type", option.Lineage()))
+		}
+		if option.Required && option.Default != nil {
+			errs = append(errs, fmt.Errorf("option %s is required and has a default (%v)", option.Lineage(), option.Default))
+		}
+		if option.Choices != nil && len(option.Choices) == 0 {
+			errs = append(errs, fmt.Errorf("option %s has an empty list of choices", option.Lineage()))
+		}
+		if slices.Contains(option.Choices, "") {
+			errs = append(errs, fmt.Errorf("option %s has an empty choice", option.Lineage()))
+		}
+		if condition := option.RequiredIf; condition != nil {
+			if _, ok := options[condition.Key]; !ok {
+				errs = append(errs, fmt.Errorf("option %s is required if %s=%v but %s is not at the same level", option.Lineage(), condition.Key, condition.Value, condition.Key))
+			}
+		}
+		errs = append(errs, validateOptions(option.Suboptions)...)
+	}
+
+	return errs
+}
+
+// NO_LOG_NAME_HINTS are the option name substrings ansible-test flags as
+// possibly sensitive when no_log isn't set explicitly
+var NO_LOG_NAME_HINTS = []string{"pass", "secret", "token", "key"}
+
+// NOT_SENSITIVE_OPTIONS are the option names known not to hold secrets even
+// though they look like they could (e.g. an email or a KMS key name)
+var NOT_SENSITIVE_OPTIONS = []string{"service_account_email", "kms_key_name"}
+
+// LooksSensitive returns true when the option name would make ansible-test
+// warn about a missing no_log
+func (o *Option) LooksSensitive() bool {
+	name := strings.ReplaceAll(o.AnsibleName(), "passive", "")
+	return slices.ContainsFunc(NO_LOG_NAME_HINTS, func(hint string) bool {
+		return strings.Contains(name, hint)
+	})
+}
+
+// NotSensitive returns true when the option sets no_log=False explicitly:
+// it is one of NOT_SENSITIVE_OPTIONS, or an override set no_log to false on an
+// option that looks sensitive. The heuristic alone never does
+func (o *Option) NotSensitive() bool {
+	if o.NoLog {
+		return false
+	}
+	return slices.Contains(NOT_SENSITIVE_OPTIONS, o.AnsibleName()) || (o.explicitNoLog && o.LooksSensitive())
+}
+
+// NOT_SENSITIVE_SUFFIXES are option name suffixes of references to a secret
+// (e.g. a Secret Manager secret version) rather than the secret itself
+var NOT_SENSITIVE_SUFFIXES = []string{"_version", "_name", "_id"}
+
+// markSensitiveOptions recursively sets no_log on the options whose name
+// contains any of the given patterns (e.g. password), unless an override set
+// no_log explicitly
+func markSensitiveOptions(options map[string]*Option, patterns []string) {
+	for name, option := range options {
+		reference := slices.ContainsFunc(NOT_SENSITIVE_SUFFIXES, func(suffix string) bool { return strings.HasSuffix(name, suffix) })
+		if !option.NoLog && !option.explicitNoLog && !reference && option.Type != TypeBool {
+			for _, pattern := range patterns {
+				if strings.Contains(name, pattern) {
+					log.Info().Msgf("option %s looks sensitive (matches %s), setting no_log", option.Lineage(), pattern)
+					option.NoLog = true
+					break
+				}
+			}
+		}
+		markSensitiveOptions(option.Suboptions, patterns)
+	}
+}
+
+// setBetaVersionAdded recursively sets the version_added of the options only
+// the beta API has (MMv1 min_version) to the given collection version. When
+// the version isn't known the description says so instead, the GA options
+// never have one
+func setBetaVersionAdded(options map[string]*Option, version string) {
+	for _, option := range options {
+		if option.Mmv1 != nil && option.Mmv1.MinVersion != "" && option.Mmv1.MinVersion != "ga" {
+			if version != "" {
+				option.VersionAdded = version
+			} else {
+				option.Description = append(option.Description, "Only available in the beta API.")
+			}
+		}
+		setBetaVersionAdded(option.Suboptions, version)
+	}
+}
+
+// setVersionAddedCollection recursively qualifies the version_added of the
+// options that have one (the configured collection version, see
+// setBetaVersionAdded) with the given collection
+func setVersionAddedCollection(options map[string]*Option, collection string) {
+	for _, option := range options {
+		if option.VersionAdded != "" {
+			option.VersionAddedCollection = collection
+		}
+		setVersionAddedCollection(option.Suboptions, collection)
+	}
+}
+
+// limitParagraphs recursively truncates option descriptions longer than
+// maxParagraphs, pointing to the API documentation for the rest
+func limitParagraphs(options map[string]*Option, maxParagraphs int, apiLink string) {
+	pointer := "See the API documentation for details."
+	if apiLink != "" {
+		pointer = fmt.Sprintf("See the API documentation for details U(%s).", apiLink)
+	}
+	for _, option := range options {
+		if len(option.Description) > maxParagraphs {
+			option.Description = append(option.Description[:maxParagraphs:maxParagraphs], pointer)
+		}
+		limitParagraphs(option.Suboptions, maxParagraphs, apiLink)
+	}
+}
+
 // getDependency analyzes the Conflicts and RequiredWith of each option in the map and creates
 // de-duped permutations for MutuallyExclusive and RequiredTogether. Returns a Dependency struct
 // with MutuallyExclusive and RequiredTogether filled in, or nil if no dependencies are found.
@@ -308,14 +993,8 @@ func getDependency(options map[string]*Option) *Dependency {
 
 	for optionName, option := range options {
 		// Handle Conflicts -> MutuallyExclusive
-		if len(option.Conflicts) > 0 {
-			conflicts := make([]string, 0, len(option.Conflicts))
-			for _, conflict := range option.Conflicts {
-				// normalize the conflict base name
-				parts := strings.Split(conflict, ".")
-				conflicts = append(conflicts, parts[len(parts)-1])
-			}
-
+		conflicts := siblingRefs(option, option.Conflicts, "conflicts with")
+		if len(conflicts) > 0 {
 			log.Debug().Msgf("option %s has conflicts with %+v", optionName, conflicts)
 
 			// Create a conflict group with the current option and its conflicts
@@ -335,14 +1014,8 @@ func getDependency(options map[string]*Option) *Dependency {
 		}
 
 		// Handle RequiredWith -> RequiredTogether
-		if len(option.RequiredWith) > 0 {
-			requiredWith := make([]string, 0, len(option.RequiredWith))
-			for _, required := range option.RequiredWith {
-				// normalize the required base name
-				parts := strings.Split(required, ".")
-				requiredWith = append(requiredWith, parts[len(parts)-1])
-			}
-
+		requiredWith := siblingRefs(option, option.RequiredWith, "is required with")
+		if len(requiredWith) > 0 {
 			log.Debug().Msgf("option %s is required together with %+v", optionName, requiredWith)
 
 			// Create a required group with the current option and its required options
@@ -362,7 +1035,63 @@ func getDependency(options map[string]*Option) *Dependency {
 		}
 	}
 
-	if len(mutuallyExclusive) == 0 && len(requiredTogether) == 0 {
+	// ExactlyOneOf -> RequiredOneOf + MutuallyExclusive, AtLeastOneOf -> RequiredOneOf
+	var requiredOneOf [][]string
+	seenOneOf := make(map[string]bool)
+	for _, optionName := range sortedKeys(options) {
+		option := options[optionName]
+		for _, refs := range [][]string{option.ExactlyOneOf, option.AtLeastOneOf} {
+			if len(refs) == 0 {
+				continue
+			}
+			group, ok := siblingGroup(optionName, refs, options)
+			if !ok {
+				log.Warn().Msgf("option %s has one-of constraints with options outside its level %v, ignoring them", option.Lineage(), refs)
+				continue
+			}
+			key := strings.Join(group, ",")
+			if !seenOneOf[key] {
+				seenOneOf[key] = true
+				requiredOneOf = append(requiredOneOf, group)
+			}
+		}
+		if len(option.ExactlyOneOf) > 0 {
+			if group, ok := siblingGroup(optionName, option.ExactlyOneOf, options); ok {
+				key := strings.Join(group, ",")
+				if !seenMutual[key] {
+					seenMutual[key] = true
+					mutuallyExclusive = append(mutuallyExclusive, group)
+				}
+			}
+		}
+	}
+
+	// RequiredIf conditions on the same sibling value are grouped together and
+	// the option is no longer required unconditionally, conditions on an option
+	// at another level can't be expressed by Ansible (see validateOptions)
+	var requiredIf []*RequiredIf
+	for _, optionName := range sortedKeys(options) {
+		option := options[optionName]
+		condition := option.RequiredIf
+		if condition == nil {
+			continue
+		}
+		if _, ok := options[condition.Key]; !ok {
+			continue
+		}
+		option.Required = false
+		option.Description = append(option.Description, fmt.Sprintf("Required when O(%s=%v).", condition.Key, condition.Value))
+		idx := slices.IndexFunc(requiredIf, func(r *RequiredIf) bool {
+			return r.Key == condition.Key && fmt.Sprint(r.Value) == fmt.Sprint(condition.Value)
+		})
+		if idx < 0 {
+			requiredIf = append(requiredIf, &RequiredIf{Key: condition.Key, Value: condition.Value})
+			idx = len(requiredIf) - 1
+		}
+		requiredIf[idx].Requirements = append(requiredIf[idx].Requirements, optionName)
+	}
+
+	if len(mutuallyExclusive) == 0 && len(requiredTogether) == 0 && len(requiredOneOf) == 0 && len(requiredIf) == 0 {
 		return nil
 	}
 
@@ -373,10 +1102,76 @@ func getDependency(options map[string]*Option) *Dependency {
 	if len(requiredTogether) > 0 {
 		dependency.RequiredTogether = requiredTogether
 	}
+	if len(requiredOneOf) > 0 {
+		dependency.RequiredOneOf = requiredOneOf
+	}
+	if len(requiredIf) > 0 {
+		dependency.RequiredIf = requiredIf
+	}
 
 	return dependency
 }
 
+// siblingRefs normalizes the given MMv1 references (e.g. network_config.0.network)
+// to option base names, dropping (with a warning) the ones at another nesting
+// level since Ansible can only express constraints between siblings
+func siblingRefs(option *Option, refs []string, relation string) []string {
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !sameLevel(option, ref) {
+			log.Warn().Msgf("option %s %s %s at another nesting level, Ansible can't enforce it", option.Lineage(), relation, ref)
+			continue
+		}
+		parts := strings.Split(ref, ".")
+		names = append(names, google.Underscore(parts[len(parts)-1]))
+	}
+	return names
+}
+
+// sameLevel returns true when the MMv1 reference (e.g. network_config.0.network)
+// points to a sibling of the given option
+func sameLevel(option *Option, ref string) bool {
+	parts := google.Reject(strings.Split(ref, "."), func(part string) bool {
+		_, err := strconv.Atoi(part)
+		return err == nil
+	})
+	parent := ""
+	if option.Parent != nil {
+		parent = google.Underscore(option.Parent.Lineage())
+	}
+	return google.Underscore(strings.Join(parts[:len(parts)-1], ".")) == parent
+}
+
+// siblingGroup normalizes MMv1 references (e.g. network_config.0.network) to
+// option names and returns them, with the given option, as a sorted group.
+// Returns false when a reference isn't an option at the same level
+func siblingGroup(optionName string, refs []string, options map[string]*Option) ([]string, bool) {
+	group := []string{optionName}
+	for _, ref := range refs {
+		parts := strings.Split(ref, ".")
+		name := google.Underscore(parts[len(parts)-1])
+		if _, ok := options[name]; !ok || !sameLevel(options[optionName], ref) {
+			return nil, false
+		}
+		if !slices.Contains(group, name) {
+			group = append(group, name)
+		}
+	}
+	sort.Strings(group)
+
+	return group, true
+}
+
+// sortedKeys returns the keys of the given options map, sorted
+func sortedKeys(m map[string]*Option) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func sortedOptions(m map[string]*Option) []*Option {
 	opts := make([]*Option, 0, len(m))
 	for _, option := range m {
@@ -0,0 +1,340 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestNormalizeDefault(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		t     Type
+		want  interface{}
+	}{
+		{"nil", nil, TypeInt, nil},
+		{"bool string", "true", TypeBool, true},
+		{"bool", false, TypeBool, false},
+		{"not a bool", "maybe", TypeBool, "maybe"},
+		{"int string", "42", TypeInt, 42},
+		{"whole float int", float64(3), TypeInt, 3},
+		{"fractional float int", 1.5, TypeInt, 1.5},
+		{"float string", "0.25", TypeFloat, 0.25},
+		{"int float", 2, TypeFloat, float64(2)},
+		{"bool str", true, TypeStr, "true"},
+		{"int str", 8080, TypeStr, "8080"},
+		{"str", "BASIC", TypeStr, "BASIC"},
+		{"list", []interface{}{"a"}, TypeList, []interface{}{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeDefault(tt.value, tt.t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeDefault(%#v, %s) = %#v, want %#v", tt.value, tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+// documentationBlock returns the YAML of the named top-level option in the
+// given DOCUMENTATION, empty if it isn't there
+func documentationBlock(doc, name string) string {
+	start := strings.Index(doc, "\n  "+name+":\n")
+	if start < 0 {
+		return ""
+	}
+	block := doc[start+1:]
+	for i, line := range strings.Split(block, "\n")[1:] {
+		if !strings.HasPrefix(line, "    ") {
+			return strings.Join(strings.Split(block, "\n")[:i+1], "\n")
+		}
+	}
+	return block
+}
+
+func TestDefaultOverride(t *testing.T) {
+	resource := testResourceYAML + `  - name: replicas
+    type: Integer
+    description: The number of replicas.
+    default_value: 1
+    default: "5"
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	if got := m.Options["replicas"].Default; got != 5 {
+		t.Errorf("replicas default = %#v, want 5", got)
+	}
+	if block := documentationBlock(m.Documentation.ToString(), "replicas"); !strings.Contains(block+"\n", "\n    default: 5\n") {
+		t.Errorf("want default: 5 in the replicas documentation:\n%s", block)
+	}
+	if block := argumentBlock(m.ArgumentSpec.ToString(), "replicas"); !strings.Contains(block, "default=5,") {
+		t.Errorf("want default=5 in the replicas argument:\n%s", block)
+	}
+}
+
+func TestListOfEnumChoices(t *testing.T) {
+	resource := testResourceYAML + `  - name: days
+    type: Array
+    description: The maintenance days.
+    item_type:
+      type: Enum
+      enum_values:
+        - MONDAY
+        - TUESDAY
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	block := documentationBlock(m.Documentation.ToString(), "days") + "\n"
+	for _, want := range []string{"\n    type: list\n", "\n    elements: str\n", "\n    choices:\n      - MONDAY\n      - TUESDAY\n"} {
+		if !strings.Contains(block, want) {
+			t.Errorf("want %q in the days documentation:\n%s", want, block)
+		}
+	}
+	argument := argumentBlock(m.ArgumentSpec.ToString(), "days")
+	for _, want := range []string{`type="list",`, `elements="str",`, `choices=["MONDAY", "TUESDAY"],`} {
+		if !strings.Contains(argument, want) {
+			t.Errorf("want %s in the days argument:\n%s", want, argument)
+		}
+	}
+}
+
+func TestValidateOptionShapes(t *testing.T) {
+	suboptions := func() map[string]*Option {
+		return map[string]*Option{"zone": {Name: "zone", Type: TypeStr}}
+	}
+	tests := []struct {
+		name   string
+		option *Option
+		want   []string
+	}{
+		{"list", &Option{Name: "x", Type: TypeList, Elements: TypeStr}, []string{}},
+		{"list of dicts", &Option{Name: "x", Type: TypeList, Elements: TypeDict, Suboptions: suboptions()}, []string{}},
+		{"dict", &Option{Name: "x", Type: TypeDict, Suboptions: suboptions()}, []string{}},
+		{"list without elements", &Option{Name: "x", Type: TypeList}, []string{"x is a list without elements"}},
+		{"dict with elements", &Option{Name: "x", Type: TypeDict, Elements: TypeStr}, []string{"x is a dict with elements str"}},
+		{"suboptions on list of scalars", &Option{Name: "x", Type: TypeList, Elements: TypeStr, Suboptions: suboptions()}, []string{"x has suboptions but its elements are str"}},
+		{"suboptions on scalar", &Option{Name: "x", Type: TypeStr, Suboptions: suboptions()}, []string{"x has suboptions but it is a str"}},
+		{
+			name:   "nested",
+			option: &Option{Name: "x", Type: TypeDict, Suboptions: map[string]*Option{"y": {Name: "y", Type: TypeList}}},
+			want:   []string{"x.y is a list without elements"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, suboption := range tt.option.Suboptions {
+				suboption.Parent = tt.option
+			}
+			if got := validateOptionShapes(map[string]*Option{"x": tt.option}); !slices.Equal(got, tt.want) {
+				t.Errorf("validateOptionShapes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParentContext(t *testing.T) {
+	resource := testResourceYAML + `  - name: settings
+    type: NestedObject
+    description: The widget settings.
+    properties:
+      - name: ipConfiguration
+        type: NestedObject
+        description: The IP configuration of the widget, IPv4 only.
+        properties:
+          - name: value
+            type: String
+            description: The value.
+`
+	tests := []struct {
+		name         string
+		length       int
+		wantValue    string
+		wantIpConfig string
+		wantTopLevel string
+	}{
+		{"disabled", 0, "The value.", "The IP configuration of the widget, IPv4 only.", "The widget settings."},
+		{"short", 20, "For settings.ip_configuration: The value.", "The IP configuration of the widget, IPv4 only.", "The widget settings."},
+		{"long", 100, "For settings.ip_configuration: The value.", "For settings: The IP configuration of the widget, IPv4 only.", "The widget settings."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.ParentContextLength = tt.length
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", config)
+			settings := m.Options["settings"]
+			ipConfig := settings.Suboptions["ip_configuration"]
+			for _, got := range []struct {
+				option *Option
+				want   string
+			}{
+				{ipConfig.Suboptions["value"], tt.wantValue},
+				{ipConfig, tt.wantIpConfig},
+				{settings, tt.wantTopLevel},
+			} {
+				if got.option.Description[0] != got.want {
+					t.Errorf("%s description = %q, want %q", got.option.Lineage(), got.option.Description[0], got.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMapTimeAndDouble(t *testing.T) {
+	tests := []struct {
+		mmv1       string
+		wantOption Type
+		wantReturn ReturnType
+	}{
+		{"Time", TypeStr, ReturnTypeStr},
+		{"Double", TypeFloat, ReturnTypeFloat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mmv1, func(t *testing.T) {
+			property := &mmv1api.Type{Name: "value", Type: tt.mmv1}
+			if got := MapMmv1ToAnsible(property); got != tt.wantOption {
+				t.Errorf("MapMmv1ToAnsible() = %s, want %s", got, tt.wantOption)
+			}
+			got, err := mapMmv1TypeToReturnType(property)
+			if err != nil || got != tt.wantReturn {
+				t.Errorf("mapMmv1TypeToReturnType() = %s (%v), want %s", got, err, tt.wantReturn)
+			}
+		})
+	}
+}
+
+func TestMaxParagraphs(t *testing.T) {
+	sentences := []string{}
+	for i := 1; i <= 20; i++ {
+		sentences = append(sentences, fmt.Sprintf("Sentence %d of the size.", i))
+	}
+	resource := strings.Replace(testResourceYAML, "    description: The widget size.\n", "    description: "+strings.Join(sentences, " ")+"\n", 1)
+	resource = "references:\n  api: https://cloud.google.com/widgets/docs/reference/rest\n" + resource
+	tests := []struct {
+		name          string
+		maxParagraphs int
+		want          []string
+	}{
+		{"no limit", 0, sentences},
+		{"limit", 5, append(slices.Clone(sentences[:5]), "See the API documentation for details U(https://cloud.google.com/widgets/docs/reference/rest).")},
+		{"under the limit", 25, sentences},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.MaxParagraphs = tt.maxParagraphs
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", config)
+			if got := m.Options["size"].Description; !slices.Equal(got, tt.want) {
+				t.Errorf("description = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrossLevelConflicts(t *testing.T) {
+	resource := strings.Replace(testResourceYAML, `    description: The widget size.
+`, `    description: The widget size.
+    conflicts:
+      - displayName
+      - settings.0.tier
+`, 1) + `  - name: settings
+    type: NestedObject
+    description: The widget settings.
+    properties:
+      - name: tier
+        type: String
+        description: The widget tier.
+`
+	var logs bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	defer func() { log.Logger = logger }()
+
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	if want := "option size conflicts with settings.0.tier at another nesting level"; !strings.Contains(logs.String(), want) {
+		t.Errorf("no %q warning in:\n%s", want, logs.String())
+	}
+	if m.Dependency == nil || !slices.ContainsFunc(m.Dependency.MutuallyExclusive, func(group []string) bool {
+		return slices.Equal(group, []string{"size", "display_name"}) || slices.Equal(group, []string{"display_name", "size"})
+	}) {
+		t.Errorf("the sibling conflict is lost: %+v", m.Dependency)
+	}
+	if strings.Contains(m.ArgumentSpec.ToString(), `"tier"]`) {
+		t.Errorf("the cross-level conflict is in the argument spec:\n%s", m.ArgumentSpec.ToString())
+	}
+}
+
+func TestCustomDescription(t *testing.T) {
+	resource := strings.Replace(testResourceYAML, `    description: The widget size.
+`, `    description: The widget size.
+    custom_description:
+      - The size of the widget. Bigger widgets cost more.
+      - See U(https://example.com/sizes) e.g. S, M or L.
+`, 1)
+	resource = strings.Replace(resource, `    description: The creation time.
+`, `    description: The creation time.
+    custom_description: When the widget was created. Set by the API.
+`, 1)
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	want := []string{"The size of the widget. Bigger widgets cost more.", "See U(https://example.com/sizes) e.g. S, M or L."}
+	if got := m.Options["size"].Description; !slices.Equal(got, want) {
+		t.Errorf("size description = %q, want %q", got, want)
+	}
+	if block := documentationBlock(m.Documentation.ToString(), "size"); !strings.Contains(block, "      - The size of the widget. Bigger widgets cost more.\n      - See U(https://example.com/sizes) e.g. S, M or L.") {
+		t.Errorf("the custom description isn't documented as-is:\n%s", block)
+	}
+	if got := m.Returns.Returns["create_time"].Description; !reflect.DeepEqual(got, []string{"When the widget was created. Set by the API."}) {
+		t.Errorf("create_time return description = %q, want the custom one", got)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		property string
+		encoding string
+		want     string
+	}{
+		{"base64", "size", "base64", "base64"},
+		{"unsupported", "size", "hex", ""},
+		{"not a string", "count", "base64", ""},
+		{"unset", "size", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := testResourceYAML + `  - name: count
+    type: Integer
+    description: The widget count.
+`
+			if tt.encoding != "" {
+				resource = strings.Replace(resource, "    description: The widget "+tt.property+".\n", "    description: The widget "+tt.property+".\n    encoding: "+tt.encoding+"\n", 1)
+			}
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+			option := m.Options[tt.property]
+			if got := option.Encoding(); got != tt.want {
+				t.Errorf("Encoding() = %q, want %q", got, tt.want)
+			}
+			documented := slices.ContainsFunc(option.Description, func(line string) bool { return strings.Contains(line, "-encoded before it is sent") })
+			if documented != (tt.want != "") {
+				t.Errorf("encoding documented = %v, want %v: %q", documented, tt.want != "", option.Description)
+			}
+		})
+	}
+}
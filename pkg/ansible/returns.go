@@ -5,10 +5,13 @@ package ansible
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
 	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
 	"github.com/rs/zerolog/log"
+	"github.com/thekad/magic-ansible/pkg/api"
 )
 
 // ReturnType represents the data types returned by the module
@@ -30,6 +33,12 @@ func (t ReturnType) ToString() string {
 	return string(t)
 }
 
+// STANDARD_RETURNS are the return values every module has
+var STANDARD_RETURNS = []string{"changed", "state", "invocation"}
+
+// DEFAULT_RETURNED is the 'returned' condition for optional return values
+const DEFAULT_RETURNED = "when set"
+
 // ReturnAttribute represents the returns section of the Ansible module documentation
 // Based on: https://docs.ansible.com/ansible/latest/dev_guide/developing_modules_documenting.html#return-block
 type ReturnAttribute struct {
@@ -52,6 +61,14 @@ type ReturnAttribute struct {
 	// Contains - for nested return values (type: dict, list/elements: dict, or complex)
 	// Optional field - map of nested ReturnAttribute objects
 	Contains map[string]*ReturnAttribute `yaml:"contains,omitempty"`
+
+	// Sample - example of the returned value
+	// Optional field - omitted when there's no example value
+	Sample interface{} `yaml:"sample,omitempty"`
+
+	// apiName is the (camelCase) API name of the field, return values are
+	// keyed by the underscored name like the options
+	apiName string
 }
 
 type ReturnBlock struct {
@@ -78,6 +95,10 @@ func mapMmv1TypeToReturnType(property *mmv1api.Type) (ReturnType, error) {
 		return ReturnTypeStr, nil
 	case "Integer":
 		return ReturnTypeInt, nil
+	case "Double":
+		return ReturnTypeFloat, nil
+	case "Time":
+		return ReturnTypeStr, nil
 	case "Boolean":
 		return ReturnTypeBool, nil
 	case "NestedObject":
@@ -88,6 +109,8 @@ func mapMmv1TypeToReturnType(property *mmv1api.Type) (ReturnType, error) {
 		return ReturnTypeDict, nil
 	case "KeyValuePairs":
 		return ReturnTypeDict, nil
+	case "ResourceRef":
+		return ReturnTypeDict, nil
 	case "Array":
 		return ReturnTypeList, nil
 	case "Enum":
@@ -102,7 +125,8 @@ func mapMmv1TypeToReturnType(property *mmv1api.Type) (ReturnType, error) {
 // NewReturnBlockFromMmv1 creates a map of Ansible return attributes from a magic-modules API Resource
 // This function extracts properties from the API Resource and converts them to Ansible module return format
 // following the specification at: https://docs.ansible.com/ansible/latest/dev_guide/developing_modules_documenting.html#return-block
-func NewReturnBlockFromMmv1(resource *mmv1api.Resource) *ReturnBlock {
+// The defaultReturned condition is used for optional fields that are not output-only
+func NewReturnBlockFromMmv1(resource *mmv1api.Resource, defaultReturned string) *ReturnBlock {
 	if resource == nil {
 		return &ReturnBlock{}
 	}
@@ -124,10 +148,11 @@ func NewReturnBlockFromMmv1(resource *mmv1api.Resource) *ReturnBlock {
 		Type:        ReturnTypeStr,
 	}
 
-	// Process properties from the API Resource
+	// Process properties from the API Resource, default_from_api inputs are
+	// filled in by the server so they are returned too
 	convertedReturns := convertPropertiesToReturns(google.Select(resource.GettableProperties(), func(p *mmv1api.Type) bool {
-		return p.Output
-	}))
+		return p.Output || p.DefaultFromApi
+	}), defaultReturned)
 
 	// Merge the converted returns with the standard returns
 	for name, returnAttr := range convertedReturns {
@@ -137,8 +162,102 @@ func NewReturnBlockFromMmv1(resource *mmv1api.Resource) *ReturnBlock {
 	return returns
 }
 
+// filterReturns removes the return values that are neither standard nor in
+// the include list (of API names)
+func filterReturns(returns map[string]*ReturnAttribute, include []string) {
+	for name, returnAttr := range returns {
+		if !slices.Contains(STANDARD_RETURNS, name) && !slices.Contains(include, returnAttr.apiName) {
+			delete(returns, name)
+		}
+	}
+}
+
+// applyCustomDescriptions recursively replaces the description of the return
+// values that have a custom_description override
+func applyCustomDescriptions(returns map[string]*ReturnAttribute, prefix string, overrides api.PropertyOverridesMap) {
+	for name, returnAttr := range returns {
+		if returnAttr.apiName != "" {
+			name = returnAttr.apiName
+		}
+		lineage := prefix + name
+		if description := overrides.Get(lineage).Description(); len(description) > 0 {
+			returnAttr.Description = description
+		}
+		applyCustomDescriptions(returnAttr.Contains, lineage+".", overrides)
+	}
+}
+
+// addReturnSamples recursively sets the sample of each return value found in
+// the given example parameters (keyed by option name), templated values are skipped
+func addReturnSamples(returns map[string]*ReturnAttribute, params map[string]interface{}) {
+	for name, returnAttr := range returns {
+		value, ok := params[name]
+		if !ok || value == nil {
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if len(returnAttr.Contains) > 0 {
+				addReturnSamples(returnAttr.Contains, v)
+				continue
+			}
+		case []interface{}:
+			if len(returnAttr.Contains) > 0 {
+				if len(v) > 0 {
+					if element, ok := v[0].(map[string]interface{}); ok {
+						addReturnSamples(returnAttr.Contains, element)
+					}
+				}
+				continue
+			}
+		case string:
+			if strings.Contains(v, "{{") {
+				continue
+			}
+		}
+		returnAttr.Sample = value
+	}
+}
+
+// newInvocationReturn returns the standard 'invocation' return attribute
+// echoing the parameters the module was called with
+func newInvocationReturn() *ReturnAttribute {
+	return &ReturnAttribute{
+		Description: "The parameters the module was invoked with, under the C(module_args) key.",
+		Returned:    "always",
+		Type:        ReturnTypeComplex,
+	}
+}
+
+// RAW_RESPONSE_RETURN is the return key of the whole API response
+const RAW_RESPONSE_RETURN = "_raw"
+
+// newRawResponseReturn returns the '_raw' return attribute holding the whole
+// API response, fields the module doesn't model included
+func newRawResponseReturn() *ReturnAttribute {
+	return &ReturnAttribute{
+		Description: []string{
+			"The resource as returned by the API, the fields the module doesn't document included.",
+			"This is not a stable interface, its content follows the API and may change without notice.",
+		},
+		Returned: "success",
+		Type:     ReturnTypeComplex,
+	}
+}
+
+// newOperationReturn returns the 'operation' return attribute of async
+// resources, set when the module doesn't wait for the operation
+func newOperationReturn() *ReturnAttribute {
+	return &ReturnAttribute{
+		Description: "The name of the long running operation, when O(wait=false).",
+		Returned:    "when O(wait=false) and the resource was changed",
+		Type:        ReturnTypeStr,
+	}
+}
+
 // convertPropertiesToReturns converts MMv1 properties to Ansible return attributes
-func convertPropertiesToReturns(properties []*mmv1api.Type) map[string]*ReturnAttribute {
+func convertPropertiesToReturns(properties []*mmv1api.Type, defaultReturned string) map[string]*ReturnAttribute {
 	if properties == nil {
 		return nil
 	}
@@ -146,7 +265,7 @@ func convertPropertiesToReturns(properties []*mmv1api.Type) map[string]*ReturnAt
 	returns := make(map[string]*ReturnAttribute)
 
 	for _, property := range properties {
-		returnName := property.Name
+		returnName := google.Underscore(property.Name)
 
 		// Create the return attribute
 		returnType, err := mapMmv1TypeToReturnType(property)
@@ -156,8 +275,9 @@ func convertPropertiesToReturns(properties []*mmv1api.Type) map[string]*ReturnAt
 
 		returnAttr := &ReturnAttribute{
 			Description: parsePropertyDescription(property),
-			Returned:    determineReturnedCondition(property),
+			Returned:    determineReturnedCondition(property, defaultReturned),
 			Type:        returnType,
+			apiName:     property.Name,
 		}
 
 		// Handle list element types
@@ -170,13 +290,18 @@ func convertPropertiesToReturns(properties []*mmv1api.Type) map[string]*ReturnAt
 
 			// If the list contains nested objects, create contains for the element type
 			if property.ItemType.Type == "NestedObject" && property.ItemType.Properties != nil {
-				returnAttr.Contains = convertPropertiesToReturns(property.ItemType.Properties)
+				returnAttr.Contains = convertPropertiesToReturns(property.ItemType.Properties, defaultReturned)
 			}
 		}
 
 		// Handle nested dictionary objects (direct contains)
 		if (returnAttr.Type == ReturnTypeDict || returnAttr.Type == ReturnTypeComplex) && property.Properties != nil {
-			returnAttr.Contains = convertPropertiesToReturns(property.Properties)
+			returnAttr.Contains = convertPropertiesToReturns(property.Properties, defaultReturned)
+		}
+
+		// References are returned like they are given, a dict holding the link
+		if property.IsA("ResourceRef") {
+			returnAttr.Contains = newResourceRefReturns(property)
 		}
 
 		returns[returnName] = returnAttr
@@ -185,14 +310,28 @@ func convertPropertiesToReturns(properties []*mmv1api.Type) map[string]*ReturnAt
 	return returns
 }
 
+// newResourceRefReturns returns the contents of a returned resource reference,
+// the link of the referenced resource is always there when the reference is
+func newResourceRefReturns(property *mmv1api.Type) map[string]*ReturnAttribute {
+	key := resourceRefKey(property)
+	return map[string]*ReturnAttribute{
+		key: {
+			Description: []string{fmt.Sprintf("The %s of the referenced %s.", key, property.Resource)},
+			Returned:    "success",
+			Type:        ReturnTypeStr,
+		},
+	}
+}
+
 // determineReturnedCondition determines when a return value is returned based on property characteristics
-func determineReturnedCondition(property *mmv1api.Type) string {
+// falling back to defaultReturned (or "when set" if empty) for optional properties
+func determineReturnedCondition(property *mmv1api.Type, defaultReturned string) string {
 	if property == nil {
 		return "success"
 	}
 
-	// Output-only properties are always returned when the resource exists
-	if property.Output {
+	// Output-only and server-defaulted properties are always returned when the resource exists
+	if property.Output || property.DefaultFromApi {
 		return "success"
 	}
 
@@ -201,6 +340,9 @@ func determineReturnedCondition(property *mmv1api.Type) string {
 		return "always"
 	}
 
-	// Optional properties are returned when set
-	return "when set"
+	// Optional properties are returned when set, unless configured otherwise
+	if defaultReturned == "" {
+		return DEFAULT_RETURNED
+	}
+	return defaultReturned
 }
@@ -0,0 +1,189 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDefaultReturned(t *testing.T) {
+	resource := testResourceYAML + `  - name: status
+    type: NestedObject
+    description: The widget status.
+    output: true
+    properties:
+      - name: code
+        type: Integer
+        description: The status code.
+        required: true
+      - name: message
+        type: String
+        description: The status message.
+`
+	tests := []struct {
+		name     string
+		resource string
+		config   string
+		want     string
+	}{
+		{"default", resource, "", "when set"},
+		{"config", resource, "success", "success"},
+		{"resource override", "default_returned: success\n" + resource, "", "success"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			if tt.config != "" {
+				config.DefaultReturned = tt.config
+			}
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", config)
+			status, ok := m.Returns.Returns["status"]
+			if !ok {
+				t.Fatalf("no status return value")
+			}
+			if got := status.Contains["message"].Returned; got != tt.want {
+				t.Errorf("optional status.message returned = %q, want %q", got, tt.want)
+			}
+			if got := status.Contains["code"].Returned; got != "always" {
+				t.Errorf("required status.code returned = %q, want always", got)
+			}
+			if got := status.Returned; got != "success" {
+				t.Errorf("output status returned = %q, want success", got)
+			}
+		})
+	}
+}
+
+func TestInvocationReturn(t *testing.T) {
+	if !NewConfig().ReturnInvocation {
+		t.Errorf("invocation isn't returned by default")
+	}
+	for _, want := range []bool{true, false} {
+		config := NewConfig()
+		config.ReturnInvocation = want
+		m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", config)
+
+		invocation, ok := m.Returns.Returns["invocation"]
+		if ok != want {
+			t.Errorf("invocation returned is %v with ReturnInvocation %v", ok, want)
+			continue
+		}
+		if want && (invocation.Returned != "always" || invocation.Type != ReturnTypeComplex) {
+			t.Errorf("invocation = %+v, want a complex value always returned", invocation)
+		}
+		if got := strings.Contains(m.Returns.ToString(), "\ninvocation:\n"); got != want {
+			t.Errorf("invocation in RETURN is %v, want %v:\n%s", got, want, m.Returns.ToString())
+		}
+	}
+}
+
+func TestRawResponseReturn(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     bool
+	}{
+		{"exposed", "expose_raw_response: true\n" + testResourceYAML, true},
+		{"default", testResourceYAML, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resource}, "Widget", nil)
+			if got := m.ExposesRawResponse(); got != tt.want {
+				t.Errorf("ExposesRawResponse() = %v, want %v", got, tt.want)
+			}
+			raw, ok := m.Returns.Returns[RAW_RESPONSE_RETURN]
+			if ok != tt.want {
+				t.Fatalf("_raw returned is %v, want %v", ok, tt.want)
+			}
+			if got := strings.Contains("\n"+m.Returns.ToString(), "\n_raw:\n"); got != tt.want {
+				t.Errorf("_raw in RETURN is %v, want %v:\n%s", got, tt.want, m.Returns.ToString())
+			}
+			if tt.want && (raw.Type != ReturnTypeComplex || !strings.Contains(fmt.Sprint(raw.Description), "not a stable interface")) {
+				t.Errorf("_raw = %+v, want an unstable complex value", raw)
+			}
+		})
+	}
+}
+
+func TestDefaultFromApiReturns(t *testing.T) {
+	resource := strings.Replace(testResourceYAML, `    description: The widget size.
+`, `    description: The widget size.
+    default_from_api: true
+`, 1)
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	size, ok := m.Returns.Returns["size"]
+	if !ok {
+		t.Fatalf("no size return value")
+	}
+	if size.Returned != "success" {
+		t.Errorf("size returned = %q, want success", size.Returned)
+	}
+	if _, ok := m.Options["size"]; !ok {
+		t.Errorf("size is no longer an option")
+	}
+	// plain inputs aren't documented as returned
+	if _, ok := m.Returns.Returns["display_name"]; ok {
+		t.Errorf("display_name is documented as returned")
+	}
+}
+
+func TestReturnsInclude(t *testing.T) {
+	resource := "returns_include:\n  - createTime\n" + testResourceYAML + `  - name: updateTime
+    type: String
+    description: The update time.
+    output: true
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	got := slices.Sorted(maps.Keys(m.Returns.Returns))
+	if want := []string{"changed", "create_time", "invocation", "state"}; !slices.Equal(got, want) {
+		t.Errorf("returns = %v, want %v", got, want)
+	}
+	if fields := m.ResponseFieldMap(); !maps.Equal(fields, map[string]string{"createTime": "create_time"}) {
+		t.Errorf("ResponseFieldMap() = %v, want createTime only", fields)
+	}
+}
+
+func TestSnakeCaseReturns(t *testing.T) {
+	resource := testResourceYAML + `  - name: lastError
+    type: NestedObject
+    description: The last error.
+    output: true
+    properties:
+      - name: errorCode
+        type: Integer
+        description: The error code.
+    custom_description: The last error of the widget.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": resource}, "Widget", nil)
+
+	for _, name := range []string{"create_time", "last_error"} {
+		if _, ok := m.Returns.Returns[name]; !ok {
+			t.Errorf("no %s return value in %v", name, slices.Sorted(maps.Keys(m.Returns.Returns)))
+		}
+	}
+	for _, name := range []string{"displayName", "createTime", "lastError"} {
+		if _, ok := m.Returns.Returns[name]; ok {
+			t.Errorf("return value %s is not underscored", name)
+		}
+	}
+	lastError := m.Returns.Returns["last_error"]
+	if got := slices.Sorted(maps.Keys(lastError.Contains)); !slices.Equal(got, []string{"error_code"}) {
+		t.Errorf("last_error contains %v, want error_code", got)
+	}
+	if got := fmt.Sprint(lastError.Description); got != "[The last error of the widget.]" {
+		t.Errorf("last_error description = %q, want the custom description of lastError", got)
+	}
+	if doc := m.Returns.ToString(); !strings.Contains(doc, "\nlast_error:\n") || !strings.Contains(doc, "    error_code:\n") {
+		t.Errorf("the RETURN block isn't underscored:\n%s", doc)
+	}
+}
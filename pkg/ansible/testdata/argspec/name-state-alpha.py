@@ -0,0 +1,36 @@
+argument_spec=dict(
+    name=dict(
+        type="str",
+        required=True,
+    ),
+    state=dict(
+        type="str",
+        default="present",
+        choices=["present", "absent"],
+    ),
+    config=dict(
+        type="dict",
+        options=dict(
+            name=dict(
+                type="str",
+            ),
+            auto_scale=dict(
+                type="bool",
+            ),
+            tier=dict(
+                type="str",
+                required=True,
+            ),
+            zone=dict(
+                type="str",
+            )
+        ),
+    ),
+    display_name=dict(
+        type="str",
+        required=True,
+    ),
+    size=dict(
+        type="str",
+    )
+)
@@ -0,0 +1,36 @@
+argument_spec=dict(
+    display_name=dict(
+        type="str",
+        required=True,
+    ),
+    name=dict(
+        type="str",
+        required=True,
+    ),
+    config=dict(
+        type="dict",
+        options=dict(
+            tier=dict(
+                type="str",
+                required=True,
+            ),
+            auto_scale=dict(
+                type="bool",
+            ),
+            name=dict(
+                type="str",
+            ),
+            zone=dict(
+                type="str",
+            )
+        ),
+    ),
+    size=dict(
+        type="str",
+    ),
+    state=dict(
+        type="str",
+        default="present",
+        choices=["present", "absent"],
+    )
+)
@@ -0,0 +1,48 @@
+#!/usr/bin/python
+# -*- coding: utf-8 -*-
+
+# Copyright 2025 Red Hat Inc.
+# SPDX-License-Identifier: Apache-2.0
+
+# published module fixture, only the DOCUMENTATION block is read
+
+DOCUMENTATION = r"""
+---
+module: gcp_widgets_widget
+short_description: Creates a GCP Widget
+description:
+  - A widget.
+options:
+  name:
+    description:
+      - The widget name.
+    type: str
+    required: true
+  size:
+    description:
+      - The widget size.
+    type: str
+    choices:
+      - SMALL
+      - LARGE
+  color:
+    description:
+      - The widget color, removed upstream.
+    type: str
+  labels:
+    description:
+      - The widget labels.
+    type: dict
+  config:
+    description:
+      - The widget config.
+    type: dict
+    suboptions:
+      count:
+        description:
+          - How many.
+        type: str
+"""
+
+EXAMPLES = r"""
+"""
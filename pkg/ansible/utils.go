@@ -6,10 +6,13 @@ package ansible
 import (
 	"bytes"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
 	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
+	"github.com/GoogleCloudPlatform/magic-modules/mmv1/google"
+	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 )
 
@@ -30,7 +33,7 @@ func parsePropertyDescription(property *mmv1api.Type) []string {
 	description = strings.Join(strings.Split(description, "\n"), " ")
 
 	// Split description by sentences
-	sentences := strings.Split(description, ". ")
+	sentences := splitSentences(description)
 	var cleanLines []string
 
 	for _, line := range sentences {
@@ -47,8 +50,9 @@ func parsePropertyDescription(property *mmv1api.Type) []string {
 	if property.Type == "ResourceRef" {
 		sourceRefDesc := []string{
 			fmt.Sprintf("This field is a reference to a %s resource in GCP.", property.Resource),
-			fmt.Sprintf("It can be specified in two ways: First, you can place a dictionary with key '%s' matching your resource.", string(property.Imports)),
-			fmt.Sprintf("Alternatively, you can add `register: name-of-resource` to a %s task and then set this field to `{{ name-of-resource }}`.", property.Resource),
+			"It can be specified in three ways: First, you can set its C(self_link) to the full name of your resource.",
+			"Second, you can set all its other suboptions, the components the full name is assembled from.",
+			fmt.Sprintf("Third, you can add `register: name-of-resource` to a %s task and then set C(self_link) to `{{ name-of-resource.%s }}`.", property.Resource, google.Underscore(string(property.Imports))),
 		}
 		cleanLines = append(cleanLines, sourceRefDesc...)
 	}
@@ -60,9 +64,73 @@ func parsePropertyDescription(property *mmv1api.Type) []string {
 	return cleanLines
 }
 
+// ABBREVIATIONS end with a period but don't end a sentence
+var ABBREVIATIONS = []string{"e.g", "i.e", "eg", "ie", "etc", "vs", "approx", "incl", "inc", "ltd", "corp"}
+
+// splitSentences splits a text on ". " like a sentence boundary, except after
+// known abbreviations (e.g. i.e.) and inside parentheses e.g. U(...) links.
+// Parentheses that are never closed don't count, so they don't swallow the
+// rest of the text
+func splitSentences(text string) []string {
+	unclosed := unclosedParentheses(text)
+	sentences := []string{}
+	depth := 0
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			if !slices.Contains(unclosed, i) {
+				depth++
+			}
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case '.':
+			if depth > 0 || i+1 >= len(text) || text[i+1] != ' ' {
+				continue
+			}
+			words := strings.Fields(text[start:i])
+			if len(words) > 0 && slices.Contains(ABBREVIATIONS, strings.ToLower(strings.TrimLeft(words[len(words)-1], "("))) {
+				continue
+			}
+			sentences = append(sentences, text[start:i])
+			start = i + 2
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+
+	return sentences
+}
+
+// unclosedParentheses returns the positions of the opening parentheses of the
+// text without a matching closing one
+func unclosedParentheses(text string) []int {
+	open := []int{}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return open
+}
+
+// PREFERRED_OPTION_KEY_ORDER is the order hand-written modules document the
+// keys of an option in, the rest of the keys follow alphabetically
+var PREFERRED_OPTION_KEY_ORDER = []string{"description", "type", "required", "default", "choices", "elements", "suboptions"}
+
 // ToYAML converts an interface to YAML format with 2-space indentation
-// Uses folded style for description fields
-func ToYAML(data interface{}) string {
+// Uses folded style for description fields. Map keys are sorted alphabetically,
+// except in option maps (those with a description and a type) where the given
+// preferred keys come first, in order
+func ToYAML(data interface{}, preferredKeys ...string) string {
 	if data == nil {
 		return ""
 	}
@@ -84,7 +152,7 @@ func ToYAML(data interface{}) string {
 	setFoldedStyleForDescriptions(node)
 
 	// Sort all map keys for consistent output
-	sortYAMLMapKeys(node)
+	sortYAMLMapKeys(node, preferredKeys)
 
 	err = encoder.Encode(node)
 	if err != nil {
@@ -164,6 +232,14 @@ func breakLineByLength(line string) []string {
 				chunks = append(chunks, currentChunk)
 			}
 			currentChunk = word
+
+			// hard-wrapping a folded scalar would insert a space in the word
+			// (e.g. a URL), so it's left whole on its own line
+			if len(word) > MAX_DESCRIPTION_LENGTH {
+				log.Warn().Msgf("word longer than %d characters can't be wrapped: %s", MAX_DESCRIPTION_LENGTH, word)
+				chunks = append(chunks, word)
+				currentChunk = ""
+			}
 		}
 	}
 
@@ -176,7 +252,8 @@ func breakLineByLength(line string) []string {
 }
 
 // sortYAMLMapKeys recursively sorts all map keys in a YAML node tree for consistent output
-func sortYAMLMapKeys(node *yaml.Node) {
+// option maps get the preferred keys first (if any)
+func sortYAMLMapKeys(node *yaml.Node, preferredKeys []string) {
 	if node == nil {
 		return
 	}
@@ -199,8 +276,21 @@ func sortYAMLMapKeys(node *yaml.Node) {
 				})
 			}
 
-			// Sort pairs by key value
-			sort.Slice(pairs, func(i, j int) bool {
+			// Sort pairs by key value, preferred keys first in option maps
+			rank := func(key string) int { return len(preferredKeys) }
+			if len(preferredKeys) > 0 && mappingHasKeys(node, "description", "type") {
+				rank = func(key string) int {
+					if i := slices.Index(preferredKeys, key); i >= 0 {
+						return i
+					}
+					return len(preferredKeys)
+				}
+			}
+			sort.SliceStable(pairs, func(i, j int) bool {
+				ri, rj := rank(pairs[i].key.Value), rank(pairs[j].key.Value)
+				if ri != rj {
+					return ri < rj
+				}
 				return pairs[i].key.Value < pairs[j].key.Value
 			})
 
@@ -213,13 +303,24 @@ func sortYAMLMapKeys(node *yaml.Node) {
 
 		// Recursively sort child nodes
 		for _, child := range node.Content {
-			sortYAMLMapKeys(child)
+			sortYAMLMapKeys(child, preferredKeys)
 		}
 
 	case yaml.SequenceNode:
 		// For sequence nodes, just recursively sort child nodes
 		for _, child := range node.Content {
-			sortYAMLMapKeys(child)
+			sortYAMLMapKeys(child, preferredKeys)
+		}
+	}
+}
+
+// mappingHasKeys returns true when the mapping node has all the given keys
+func mappingHasKeys(node *yaml.Node, keys ...string) bool {
+	found := 0
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if slices.Contains(keys, node.Content[i].Value) {
+			found++
 		}
 	}
+	return found == len(keys)
 }
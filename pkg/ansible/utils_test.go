@@ -0,0 +1,63 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ansible
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "abbreviation",
+			text: "The zone of the instance, e.g. us-central1-a. It must be in the region.",
+			want: []string{"The zone of the instance, e.g. us-central1-a", "It must be in the region."},
+		},
+		{
+			name: "ip address",
+			text: "The IP address e.g. 192.168.0.1 of the peer. Only IPv4 is supported.",
+			want: []string{"The IP address e.g. 192.168.0.1 of the peer", "Only IPv4 is supported."},
+		},
+		{
+			name: "trailing url",
+			text: "The network name. See https://cloud.google.com/vpc/docs/vpc.",
+			want: []string{"The network name", "See https://cloud.google.com/vpc/docs/vpc."},
+		},
+		{
+			name: "unbalanced parenthesis",
+			text: "The size (in GB. Defaults to 10. Must be positive.",
+			want: []string{"The size (in GB", "Defaults to 10", "Must be positive."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSentences(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("splitSentences(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakLineByLengthLongWord(t *testing.T) {
+	url := "https://cloud.google.com/" + strings.Repeat("a", 200-len("https://cloud.google.com/"))
+	line := "See the documentation at " + url + " for the supported values of this field."
+
+	got := breakLineByLength(line)
+	want := []string{"See the documentation at", url, "for the supported values of this field."}
+	if !slices.Equal(got, want) {
+		t.Errorf("breakLineByLength() = %q, want %q", got, want)
+	}
+	for _, chunk := range got {
+		if chunk != url && len(chunk) > MAX_DESCRIPTION_LENGTH {
+			t.Errorf("chunk longer than %d characters: %q", MAX_DESCRIPTION_LENGTH, chunk)
+		}
+	}
+}
@@ -81,15 +81,47 @@ func (p *Product) ApplyOverrides(rootNode *yaml.Node) {
 	overrideYAML(rootNode, p.OverridesDir, p.File)
 }
 
+// ResourceSelfLink returns the (MMv1 template) self link of the named resource
+// of this product e.g. projects/{{project}}/locations/{{location}}/clusters/{{cluster_id}},
+// empty if the resource file cannot be read. Only the link keys are read so
+// the resource doesn't have to be selected for generation
+func (p *Product) ResourceSelfLink(name string) string {
+	yamlPath := filepath.Join(filepath.Dir(p.File), fmt.Sprintf("%s.yaml", name))
+	yamlData, err := os.ReadFile(yamlPath)
+	if err != nil {
+		log.Debug().Msgf("cannot open resource file %s: %v", yamlPath, err)
+		return ""
+	}
+
+	links := struct {
+		BaseUrl  string `yaml:"base_url"`
+		SelfLink string `yaml:"self_link"`
+	}{}
+	if err := yaml.Unmarshal(yamlData, &links); err != nil {
+		log.Debug().Msgf("cannot unmarshal resource file %s: %v", yamlPath, err)
+		return ""
+	}
+	if links.SelfLink != "" {
+		return links.SelfLink
+	}
+	if links.BaseUrl != "" {
+		return fmt.Sprintf("%s/{{name}}", strings.Split(links.BaseUrl, "?")[0])
+	}
+
+	return ""
+}
+
 // Resource is a representation of a file found in the products directory
 // from magic-modules clone e.g. mmv1/products/<product>/<resource>.yaml
 type Resource struct {
-	Name         string
-	File         string
-	Mmv1         *mmv1api.Resource
-	Parent       *Product
-	TemplateDir  string
-	OverridesDir string
+	Name              string
+	File              string
+	Mmv1              *mmv1api.Resource
+	Parent            *Product
+	TemplateDir       string
+	OverridesDir      string
+	Overrides         *ResourceOverrides
+	PropertyOverrides PropertyOverridesMap
 }
 
 // NewResource is a constructor that returns an initialized Resource type
@@ -104,6 +136,7 @@ func NewResource(yamlPath string, parent *Product, templateDir string, overrides
 		Parent:       parent,
 		TemplateDir:  templateDir,
 		OverridesDir: overridesDir,
+		Overrides:    &ResourceOverrides{},
 	}
 }
 
@@ -123,6 +156,8 @@ func (r *Resource) Unmarshal() error {
 		return fmt.Errorf("cannot unmarshal file: %v", r.File)
 	}
 	r.ApplyOverrides(&rootNode)
+	r.Overrides = extractResourceOverrides(&rootNode)
+	r.PropertyOverrides = extractPropertyOverrides(&rootNode)
 	r.patchExamples(&rootNode)
 
 	// marshal the patched data back into a string
@@ -0,0 +1,301 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// ResourceOverrides holds the resource-level override keys that only make sense
+// for the Ansible generator, like PropertyOverrides these are removed from the
+// YAML before it is handed to the MMv1 parser
+type ResourceOverrides struct {
+	// DefaultReturned is the RETURN 'returned' condition for optional fields
+	DefaultReturned string `yaml:"default_returned,omitempty"`
+
+	// ConflictIsIdempotent set to false makes a 409 on create fail the module
+	// instead of being treated as an already existing resource
+	ConflictIsIdempotent *bool `yaml:"conflict_is_idempotent,omitempty"`
+
+	// DeleteNotFoundIsOk set to false makes state=absent fail when the
+	// resource doesn't exist (or a 404 on delete) instead of a no-op
+	DeleteNotFoundIsOk *bool `yaml:"delete_not_found_is_ok,omitempty"`
+
+	// SupportsCheckMode set to false makes the module skip check mode runs
+	// instead of predicting the changes
+	SupportsCheckMode *bool `yaml:"supports_check_mode,omitempty"`
+
+	// RollbackOnFailure makes the module delete the resource a failed create
+	// left behind (e.g. a long running operation failing halfway)
+	RollbackOnFailure bool `yaml:"rollback_on_failure,omitempty"`
+
+	// DangerousDelete makes the module refuse to delete the resource unless
+	// the force option is set
+	DangerousDelete bool `yaml:"dangerous_delete,omitempty"`
+
+	// ExposeRawResponse adds the whole (unmodeled) API response to the module
+	// result under the _raw key
+	ExposeRawResponse bool `yaml:"expose_raw_response,omitempty"`
+
+	// ReturnsInclude restricts the documented and returned fields to these
+	// (top-level API names), besides the standard return values
+	ReturnsInclude []string `yaml:"returns_include,omitempty"`
+
+	// Attributes overrides the support level (full, partial, none or N/A) of
+	// the documented module attributes e.g. diff_mode: full
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+
+	// StateDefault overrides the default of the state option (present) e.g.
+	// absent for cleanup-oriented modules
+	StateDefault string `yaml:"state_default,omitempty"`
+
+	// Deprecated marks the generated module as deprecated
+	Deprecated *DeprecatedOverride `yaml:"deprecated,omitempty"`
+
+	// PatchOnly restricts the in-place updates to these (top-level API name)
+	// fields, changes to any other field fail the module
+	PatchOnly []string `yaml:"patch_only,omitempty"`
+}
+
+// DeprecatedOverride is the deprecation of a resource module e.g. because it
+// was renamed or removed upstream
+type DeprecatedOverride struct {
+	// RemovedIn is the collection version the module will be removed in
+	RemovedIn string `yaml:"removed_in"`
+
+	// Why is the reason of the deprecation, defaults to the MMv1
+	// deprecation_message
+	Why string `yaml:"why,omitempty"`
+
+	// Alternative is what to use instead e.g. the replacing module
+	Alternative string `yaml:"alternative,omitempty"`
+}
+
+// PropertyOverrides holds the property-level override keys that only make sense
+// for the Ansible generator. These keys are not part of the MMv1 schema so they
+// are removed from the YAML before it is handed to the (strict) MMv1 parser
+type PropertyOverrides struct {
+	// AsBool retypes a two-valued enum (e.g. ENABLED/DISABLED) as a bool option
+	AsBool bool `yaml:"as_bool,omitempty"`
+
+	// StrictChoices set to false documents the enum values but doesn't enforce
+	// them in the argument spec, leaving the validation to the API
+	StrictChoices *bool `yaml:"strict_choices,omitempty"`
+
+	// MergeStrategy is how user input is merged with the existing resource
+	// state on update, one of replace, merge or append
+	MergeStrategy string `yaml:"merge_strategy,omitempty"`
+
+	// RequiredOnCreate set to true makes a required property only required
+	// when state=present, false keeps it always required. By default this is
+	// inferred from whether the property is part of the resource link
+	RequiredOnCreate *bool `yaml:"required_on_create,omitempty"`
+
+	// ClassName overrides the python class name generated for a nested object
+	ClassName string `yaml:"class_name,omitempty"`
+
+	// Default overrides the default value of the option (MMv1 default_value)
+	Default interface{} `yaml:"default,omitempty"`
+
+	// Aliases are alternate (e.g. historical) names accepted for the option
+	Aliases []string `yaml:"aliases,omitempty"`
+
+	// CustomDescription replaces the MMv1 description as-is (no sentence
+	// splitting or cleanup), either a string or a list of paragraphs
+	CustomDescription interface{} `yaml:"custom_description,omitempty"`
+
+	// Encoding is how the value is encoded in the API, only base64 is
+	// supported: the value is encoded on requests and decoded on responses
+	Encoding string `yaml:"encoding,omitempty"`
+
+	// NoLog set to true hides the option value from the logs, false disables
+	// the sensitive name heuristic (and the MMv1 sensitive flag) for it
+	NoLog *bool `yaml:"no_log,omitempty"`
+
+	// RequiredIf makes the option required when a sibling property has a value
+	RequiredIf *RequiredIfOverride `yaml:"required_if,omitempty"`
+}
+
+// RequiredIfOverride is a conditional requirement on a sibling property, e.g.
+// secondaryConfig is required when clusterType is SECONDARY
+type RequiredIfOverride struct {
+	// Key is the MMv1 name of the sibling property
+	Key string `yaml:"key"`
+
+	// Value is the value of the sibling property that makes the option required
+	Value interface{} `yaml:"value"`
+}
+
+// PropertyOverridesMap maps a property lineage (dot-separated MMv1 property
+// names e.g. networkConfig.network) to its Ansible-specific overrides
+type PropertyOverridesMap map[string]*PropertyOverrides
+
+// Get returns the overrides for the given property lineage, or an empty
+// PropertyOverrides if none were defined
+func (m PropertyOverridesMap) Get(lineage string) *PropertyOverrides {
+	if po, ok := m[lineage]; ok {
+		return po
+	}
+	return &PropertyOverrides{}
+}
+
+// Description returns the custom_description paragraphs, nil if not set
+func (po *PropertyOverrides) Description() []string {
+	switch description := po.CustomDescription.(type) {
+	case string:
+		return []string{description}
+	case []interface{}:
+		paragraphs := make([]string, 0, len(description))
+		for _, paragraph := range description {
+			paragraphs = append(paragraphs, fmt.Sprint(paragraph))
+		}
+		return paragraphs
+	case nil:
+		return nil
+	default:
+		log.Warn().Msgf("custom_description must be a string or a list of strings, ignoring %v", description)
+		return nil
+	}
+}
+
+// extractResourceOverrides removes the Ansible-specific top-level keys from the
+// given resource YAML and returns them
+func extractResourceOverrides(rootNode *yaml.Node) *ResourceOverrides {
+	overrides := &ResourceOverrides{}
+	node := rootNode
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return overrides
+		}
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return overrides
+	}
+
+	extracted := removeMappingKeys(node, yamlKeys(ResourceOverrides{}))
+	if len(extracted.Content) > 0 {
+		if err := extracted.Decode(overrides); err != nil {
+			log.Error().Msgf("cannot decode resource overrides: %v", err)
+		}
+	}
+
+	return overrides
+}
+
+// propertyListKeys are the resource keys holding lists of MMv1 properties
+var propertyListKeys = []string{"virtual_fields", "parameters", "properties"}
+
+// extractPropertyOverrides removes the Ansible-specific keys from every property
+// in the given resource YAML and returns them indexed by property lineage
+func extractPropertyOverrides(rootNode *yaml.Node) PropertyOverridesMap {
+	overrides := PropertyOverridesMap{}
+	node := rootNode
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return overrides
+		}
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return overrides
+	}
+
+	for _, key := range propertyListKeys {
+		if listNode := mappingValue(node, key); listNode != nil {
+			walkPropertyOverrides(listNode, "", overrides)
+		}
+	}
+
+	return overrides
+}
+
+// walkPropertyOverrides recursively extracts the overrides from a list of
+// properties, nested properties and array item properties included
+func walkPropertyOverrides(listNode *yaml.Node, prefix string, overrides PropertyOverridesMap) {
+	if listNode.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, propertyNode := range listNode.Content {
+		if propertyNode.Kind != yaml.MappingNode {
+			continue
+		}
+		nameNode := mappingValue(propertyNode, "name")
+		if nameNode == nil {
+			continue
+		}
+		lineage := prefix + nameNode.Value
+
+		extracted := removeMappingKeys(propertyNode, yamlKeys(PropertyOverrides{}))
+		if len(extracted.Content) > 0 {
+			po := &PropertyOverrides{}
+			if err := extracted.Decode(po); err != nil {
+				log.Error().Msgf("cannot decode overrides for property %s: %v", lineage, err)
+			} else {
+				overrides[lineage] = po
+			}
+		}
+
+		if nested := mappingValue(propertyNode, "properties"); nested != nil {
+			walkPropertyOverrides(nested, lineage+".", overrides)
+		}
+		if itemType := mappingValue(propertyNode, "item_type"); itemType != nil && itemType.Kind == yaml.MappingNode {
+			if nested := mappingValue(itemType, "properties"); nested != nil {
+				walkPropertyOverrides(nested, lineage+".", overrides)
+			}
+		}
+	}
+}
+
+// mappingValue returns the value for the given key in a mapping node (not recursive)
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Kind == yaml.ScalarNode && node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKeys removes the given keys from a mapping node and returns them
+// as a new mapping node
+func removeMappingKeys(node *yaml.Node, keys []string) *yaml.Node {
+	removed := &yaml.Node{Kind: yaml.MappingNode}
+	kept := make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+		if keyNode.Kind == yaml.ScalarNode && slices.Contains(keys, keyNode.Value) {
+			removed.Content = append(removed.Content, keyNode, valueNode)
+			continue
+		}
+		kept = append(kept, keyNode, valueNode)
+	}
+	node.Content = kept
+
+	return removed
+}
+
+// yamlKeys returns the YAML key names declared in the struct tags of the given value
+func yamlKeys(v interface{}) []string {
+	keys := []string{}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
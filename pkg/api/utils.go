@@ -6,6 +6,7 @@ package api
 import (
 	"os"
 	"path"
+	"slices"
 	"strings"
 
 	"github.com/rs/zerolog/log"
@@ -69,11 +70,44 @@ func overrideYAML(rootNode *yaml.Node, overridesDir string, yamlFile string) {
 	}
 
 	// merge the override data into the root node
-	mergeYAMLNodes(rootNode, &overrideNode)
+	mergeKeys := extractMergeKeys(&overrideNode)
+	mergeYAMLNodes(rootNode, &overrideNode, mergeKeys)
 }
 
-// mergeYAMLNodes merges the override node into the root node
-func mergeYAMLNodes(root, override *yaml.Node) {
+// DEFAULT_MERGE_KEYS are the keys identifying the dictionaries of a list when
+// merging overrides, override files can set their own with _merge_keys
+var DEFAULT_MERGE_KEYS = []string{"name", "id"}
+
+// extractMergeKeys removes the top-level _merge_keys directive from the given
+// override document and returns its keys, or DEFAULT_MERGE_KEYS if not set
+func extractMergeKeys(overrideNode *yaml.Node) []string {
+	root := overrideNode
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return DEFAULT_MERGE_KEYS
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "_merge_keys" {
+			continue
+		}
+		keys := []string{}
+		if err := root.Content[i+1].Decode(&keys); err != nil || len(keys) == 0 {
+			log.Warn().Msgf("_merge_keys must be a non-empty list of keys, using %v", DEFAULT_MERGE_KEYS)
+			keys = DEFAULT_MERGE_KEYS
+		}
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		return keys
+	}
+
+	return DEFAULT_MERGE_KEYS
+}
+
+// mergeYAMLNodes merges the override node into the root node, list items are
+// matched by the given identifying keys
+func mergeYAMLNodes(root, override *yaml.Node, identifyingKeys []string) {
 	if root == nil || override == nil {
 		return
 	}
@@ -81,20 +115,20 @@ func mergeYAMLNodes(root, override *yaml.Node) {
 	// Handle document nodes by merging their content
 	if root.Kind == yaml.DocumentNode && override.Kind == yaml.DocumentNode {
 		if len(root.Content) > 0 && len(override.Content) > 0 {
-			mergeYAMLNodes(root.Content[0], override.Content[0])
+			mergeYAMLNodes(root.Content[0], override.Content[0], identifyingKeys)
 		}
 		return
 	}
 
 	// Handle mapping nodes (objects)
 	if root.Kind == yaml.MappingNode && override.Kind == yaml.MappingNode {
-		mergeMappingNodes(root, override)
+		mergeMappingNodes(root, override, identifyingKeys)
 		return
 	}
 
 	// Handle sequence nodes (arrays)
 	if root.Kind == yaml.SequenceNode && override.Kind == yaml.SequenceNode {
-		overrideSequenceNodes(root, override)
+		overrideSequenceNodes(root, override, identifyingKeys)
 		return
 	}
 
@@ -106,7 +140,7 @@ func mergeYAMLNodes(root, override *yaml.Node) {
 }
 
 // mergeMappingNodes merges two mapping (object) nodes
-func mergeMappingNodes(node, override *yaml.Node) {
+func mergeMappingNodes(node, override *yaml.Node, identifyingKeys []string) {
 	// Iterate through override pairs (key, value, key, value, ...)
 	for i := 0; i < len(override.Content); i += 2 {
 		if i+1 >= len(override.Content) {
@@ -116,6 +150,12 @@ func mergeMappingNodes(node, override *yaml.Node) {
 		overrideKey := override.Content[i]
 		overrideValue := override.Content[i+1]
 
+		// some_key: {_drop: true} removes the key altogether
+		if shouldDropItem(overrideValue) {
+			removeKeyFromMapping(node, overrideKey.Value)
+			continue
+		}
+
 		// Find matching key in root
 		found := false
 		for j := 0; j < len(node.Content); j += 2 {
@@ -128,7 +168,12 @@ func mergeMappingNodes(node, override *yaml.Node) {
 
 			if nodeKey.Value == overrideKey.Value {
 				// Key exists, merge the values
-				mergeYAMLNodes(nodeValue, overrideValue)
+				if slices.Contains(PROPERTY_LIST_KEYS, nodeKey.Value) &&
+					nodeValue.Kind == yaml.SequenceNode && overrideValue.Kind == yaml.SequenceNode {
+					mergePropertySequences(nodeValue, overrideValue, identifyingKeys)
+				} else {
+					mergeYAMLNodes(nodeValue, overrideValue, identifyingKeys)
+				}
 				found = true
 				break
 			}
@@ -142,15 +187,20 @@ func mergeMappingNodes(node, override *yaml.Node) {
 }
 
 // overrideSequenceNodes intelligently handles sequence node overrides
+// - If the list has a {_replace: true} item, the rest of the items replace the list
 // - If the list contains dictionaries, it merges matching dictionaries by key
 // - If the list contains scalars, it replaces the entire list
-func overrideSequenceNodes(node, override *yaml.Node) {
+func overrideSequenceNodes(node, override *yaml.Node, identifyingKeys []string) {
 	if len(override.Content) == 0 {
 		// If override is empty, clear the original list
 		node.Content = override.Content
 		return
 	}
 
+	if replaceSequence(node, override) {
+		return
+	}
+
 	// Check if the override list contains dictionaries
 	containsDictionaries := false
 	for _, item := range override.Content {
@@ -167,15 +217,67 @@ func overrideSequenceNodes(node, override *yaml.Node) {
 	}
 
 	// Handle dictionary merging within the list
-	mergeSequenceDictionaries(node, override)
+	mergeSequenceDictionaries(node, override, identifyingKeys)
 }
 
-// mergeSequenceDictionaries merges dictionaries within sequence nodes
-// It attempts to match dictionaries by common identifying keys
-func mergeSequenceDictionaries(node, override *yaml.Node) {
-	// Common keys used to identify matching dictionary items in lists
-	identifyingKeys := []string{"name", "id"}
+// PROPERTY_LIST_KEYS hold lists of (nested) properties, merged by property name
+var PROPERTY_LIST_KEYS = []string{"properties", "suboptions"}
+
+// replaceSequence replaces the node items with the override ones when the
+// override has a {_replace: true} item, returns true if it did
+func replaceSequence(node, override *yaml.Node) bool {
+	items := make([]*yaml.Node, 0, len(override.Content))
+	for _, item := range override.Content {
+		if !hasDirective(item, "_replace") {
+			items = append(items, item)
+		}
+	}
+	if len(items) == len(override.Content) {
+		return false
+	}
+
+	node.Content = items
+	return true
+}
+
+// mergePropertySequences merges a list of property overrides into a list of
+// properties matching them by name only, so nested properties can be
+// overridden without replaying the whole structure
+func mergePropertySequences(node, override *yaml.Node, identifyingKeys []string) {
+	if replaceSequence(node, override) {
+		return
+	}
 
+	for _, overrideItem := range override.Content {
+		overrideName := findIdentifyingKeyValue(overrideItem, []string{"name"})
+		if overrideName == nil {
+			log.Warn().Msgf("property override at line %d has no name, ignoring it", overrideItem.Line)
+			continue
+		}
+
+		var originalItem *yaml.Node
+		for _, item := range node.Content {
+			if name := findIdentifyingKeyValue(item, []string{"name"}); name != nil && name.value == overrideName.value {
+				originalItem = item
+				break
+			}
+		}
+
+		switch {
+		case originalItem == nil:
+			log.Warn().Msgf("property override %s doesn't match any property, adding it as a new one", overrideName.value)
+			node.Content = append(node.Content, overrideItem)
+		case shouldDropItem(overrideItem):
+			removeItemFromSequence(node, originalItem)
+		default:
+			mergeMappingNodes(originalItem, overrideItem, identifyingKeys)
+		}
+	}
+}
+
+// mergeSequenceDictionaries merges dictionaries within sequence nodes
+// It attempts to match dictionaries by the given identifying keys
+func mergeSequenceDictionaries(node, override *yaml.Node, identifyingKeys []string) {
 	for _, overrideItem := range override.Content {
 		if overrideItem.Kind != yaml.MappingNode {
 			// Skip non-dictionary items in override
@@ -208,7 +310,7 @@ func mergeSequenceDictionaries(node, override *yaml.Node) {
 					removeItemFromSequence(node, originalItem)
 				} else {
 					// Merge the dictionaries
-					mergeMappingNodes(originalItem, overrideItem)
+					mergeMappingNodes(originalItem, overrideItem, identifyingKeys)
 				}
 				matchFound = true
 				break
@@ -263,11 +365,16 @@ func findIdentifyingKeyValue(mappingNode *yaml.Node, identifyingKeys []string) *
 
 // shouldDropItem checks if a dictionary item should be dropped based on the _drop key
 func shouldDropItem(item *yaml.Node) bool {
+	return hasDirective(item, "_drop")
+}
+
+// hasDirective checks if a dictionary item sets the given directive (e.g. _drop) to true
+func hasDirective(item *yaml.Node, directive string) bool {
 	if item.Kind != yaml.MappingNode {
 		return false
 	}
 
-	// Look for the _drop key in the mapping
+	// Look for the directive key in the mapping
 	for i := 0; i < len(item.Content); i += 2 {
 		if i+1 >= len(item.Content) {
 			break
@@ -276,7 +383,7 @@ func shouldDropItem(item *yaml.Node) bool {
 		keyNode := item.Content[i]
 		valueNode := item.Content[i+1]
 
-		if keyNode.Kind == yaml.ScalarNode && keyNode.Value == "_drop" {
+		if keyNode.Kind == yaml.ScalarNode && keyNode.Value == directive {
 			if valueNode.Kind == yaml.ScalarNode {
 				// Check if the value is "true" (string) or boolean true
 				return valueNode.Value == "true" || valueNode.Tag == "!!bool" && valueNode.Value == "true"
@@ -287,6 +394,21 @@ func shouldDropItem(item *yaml.Node) bool {
 	return false
 }
 
+// removeKeyFromMapping removes the given key (and its value) from a mapping node
+func removeKeyFromMapping(mappingNode *yaml.Node, key string) {
+	if mappingNode.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(mappingNode.Content); i += 2 {
+		if mappingNode.Content[i].Value == key {
+			mappingNode.Content = append(mappingNode.Content[:i], mappingNode.Content[i+2:]...)
+			return
+		}
+	}
+	log.Warn().Msgf("cannot drop key %s, it doesn't exist", key)
+}
+
 // removeItemFromSequence removes a specific item from a sequence node
 func removeItemFromSequence(sequenceNode *yaml.Node, itemToRemove *yaml.Node) {
 	if sequenceNode.Kind != yaml.SequenceNode {
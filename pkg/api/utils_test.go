@@ -0,0 +1,189 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	mmv1api "github.com/GoogleCloudPlatform/magic-modules/mmv1/api"
+)
+
+const testResourceYAML = `name: Widget
+base_url: projects/{{project}}/widgets
+parameters:
+  - name: project
+    type: String
+    description: The project.
+    url_param_only: true
+  - name: location
+    type: String
+    description: The location.
+    url_param_only: true
+properties:
+  - name: displayName
+    type: String
+    description: The display name.
+`
+
+// unmarshalWithOverride writes the given widget resource and override file in
+// a temporary products/overrides layout and unmarshals the resource
+func unmarshalWithOverride(t *testing.T, resourceYAML, override string) *Resource {
+	t.Helper()
+	dir := t.TempDir()
+	productsDir := filepath.Join(dir, "products", "widgets")
+	overridesDir := filepath.Join(dir, "overrides")
+	for _, d := range []string{productsDir, filepath.Join(overridesDir, "widgets")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resourceFile := filepath.Join(productsDir, "Widget.yaml")
+	if err := os.WriteFile(resourceFile, []byte(resourceYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(overridesDir, "widgets", "Widget.yaml"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	product := NewProduct(filepath.Join(productsDir, "product.yaml"), dir, overridesDir)
+	resource := NewResource(resourceFile, product, dir, overridesDir)
+	if err := resource.Unmarshal(); err != nil {
+		t.Fatal(err)
+	}
+	return resource
+}
+
+func TestOverrideSequenceNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		want     []string
+		check    func(t *testing.T, r *Resource)
+	}{
+		{
+			name: "merge",
+			override: `parameters:
+  - name: location
+    description: The merged location.
+`,
+			want: []string{"project", "location"},
+			check: func(t *testing.T, r *Resource) {
+				if got := r.Mmv1.Parameters[1].Description; got != "The merged location." {
+					t.Errorf("description = %q, want the merged one", got)
+				}
+				if !r.Mmv1.Parameters[1].UrlParamOnly {
+					t.Errorf("url_param_only was lost in the merge")
+				}
+			},
+		},
+		{
+			name: "append",
+			override: `parameters:
+  - name: zone
+    type: String
+    description: The zone.
+`,
+			want: []string{"project", "location", "zone"},
+		},
+		{
+			name: "drop",
+			override: `parameters:
+  - name: location
+    _drop: true
+`,
+			want: []string{"project"},
+		},
+		{
+			name: "replace",
+			override: `parameters:
+  - _replace: true
+  - name: region
+    type: String
+    description: The region.
+`,
+			want: []string{"region"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := unmarshalWithOverride(t, testResourceYAML, tt.override)
+			got := []string{}
+			for _, p := range r.Mmv1.Parameters {
+				got = append(got, p.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parameters = %v, want %v", got, tt.want)
+			}
+			if len(r.Mmv1.Properties) != 1 || r.Mmv1.Properties[0].Name != "displayName" {
+				t.Errorf("properties changed by a parameters override")
+			}
+			if tt.check != nil {
+				tt.check(t, r)
+			}
+		})
+	}
+}
+
+func TestOverrideNestedProperties(t *testing.T) {
+	resource := testResourceYAML + `  - name: settings
+    type: NestedObject
+    description: The settings.
+    properties:
+      - name: network
+        type: NestedObject
+        description: The network settings.
+        properties:
+          - name: cidr
+            type: String
+            description: The range.
+          - name: gateway
+            type: String
+            description: The gateway.
+`
+	override := `properties:
+  - name: settings
+    properties:
+      - name: network
+        properties:
+          - name: cidr
+            description: The IPv4 range of the network.
+          - name: gateway
+            _drop: true
+          - name: mtu
+            type: Integer
+            description: The MTU.
+`
+	r := unmarshalWithOverride(t, resource, override)
+
+	if names := propertyNames(r.Mmv1.Properties); !slices.Equal(names, []string{"displayName", "settings"}) {
+		t.Fatalf("properties = %v, want displayName and settings", names)
+	}
+	settings := r.Mmv1.Properties[1]
+	if settings.Description != "The settings." || len(settings.Properties) != 1 {
+		t.Fatalf("settings = %+v, want it untouched", settings)
+	}
+	network := settings.Properties[0]
+	if network.Description != "The network settings." {
+		t.Errorf("network description = %q, want the original one", network.Description)
+	}
+	if names := propertyNames(network.Properties); !slices.Equal(names, []string{"cidr", "mtu"}) {
+		t.Fatalf("network properties = %v, want cidr and mtu", names)
+	}
+	if cidr := network.Properties[0]; cidr.Description != "The IPv4 range of the network." || cidr.Type != "String" {
+		t.Errorf("cidr = %q (%s), want the merged description and the original type", cidr.Description, cidr.Type)
+	}
+}
+
+// propertyNames returns the names of the given properties, in order
+func propertyNames(properties []*mmv1api.Type) []string {
+	names := []string{}
+	for _, p := range properties {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -1,6 +1,9 @@
 // Copyright 2025 Red Hat Inc.
 // SPDX-License-Identifier: Apache-2.0
 
+// Package templates renders the generated files (modules, lookup plugins and
+// integration tests) out of the ansible package types, it is the only
+// template package and the single place the template functions are defined
 package templates
 
 import (
@@ -16,15 +19,30 @@ import (
 	"github.com/thekad/magic-ansible/pkg/ansible"
 )
 
+const (
+	DEFAULT_MODULE_PATH = "plugins/modules"
+	DEFAULT_TEST_PATH   = "tests/integration/targets"
+
+	// UNIT_TEST_PATH is where the module unit tests are written, relative to
+	// the output folder
+	UNIT_TEST_PATH = "tests/unit/plugins/modules"
+)
+
 type TemplateData struct {
 	TemplateDirectory        string
 	OutputFolder             string
 	ModuleDirectory          string
+	LookupDirectory          string
+	ModuleUtilsDirectory     string
 	IntegrationTestDirectory string
+	UnitTestDirectory        string
 	OverWrite                bool
 }
 
-func NewTemplateData(templateDirectory, outputFolder string, overWrite bool) *TemplateData {
+// NewTemplateData is a constructor that returns an initialized TemplateData,
+// the module and test paths are relative to the output folder and default to
+// DEFAULT_MODULE_PATH and DEFAULT_TEST_PATH when empty
+func NewTemplateData(templateDirectory, outputFolder, modulePath, testPath string, overWrite bool) *TemplateData {
 	absTemplateDirectory, err := filepath.Abs(templateDirectory)
 	if err != nil {
 		log.Panic().Err(err)
@@ -33,11 +51,20 @@ func NewTemplateData(templateDirectory, outputFolder string, overWrite bool) *Te
 	if err != nil {
 		log.Panic().Err(err)
 	}
+	if modulePath == "" {
+		modulePath = DEFAULT_MODULE_PATH
+	}
+	if testPath == "" {
+		testPath = DEFAULT_TEST_PATH
+	}
 	return &TemplateData{
 		TemplateDirectory:        absTemplateDirectory,
 		OutputFolder:             absOutputFolder,
-		ModuleDirectory:          path.Join(absOutputFolder, "plugins", "modules"),
-		IntegrationTestDirectory: path.Join(absOutputFolder, "tests", "integration", "targets"),
+		ModuleDirectory:          path.Join(absOutputFolder, modulePath),
+		LookupDirectory:          path.Join(absOutputFolder, "plugins", "lookup"),
+		ModuleUtilsDirectory:     path.Join(absOutputFolder, "plugins", "module_utils"),
+		IntegrationTestDirectory: path.Join(absOutputFolder, testPath),
+		UnitTestDirectory:        path.Join(absOutputFolder, UNIT_TEST_PATH),
 		OverWrite:                overWrite,
 	}
 }
@@ -120,33 +147,110 @@ func (td *TemplateData) GenerateCode(module *ansible.Module) error {
 	return nil
 }
 
+// GenerateLookup writes the read-only lookup plugin of a product
+func (td *TemplateData) GenerateLookup(lookup *ansible.Lookup) error {
+	if err := os.MkdirAll(td.LookupDirectory, 0755); err != nil {
+		return fmt.Errorf("error creating lookup directory: %v", err)
+	}
+
+	lookupFile := path.Join(td.LookupDirectory, fmt.Sprintf("%s.py", lookup))
+
+	if err := td.writeFile(lookupFile, "plugins/lookup.tmpl", lookup); err != nil {
+		return fmt.Errorf("error generating lookup file: %v", err)
+	}
+
+	return nil
+}
+
+// GenerateModuleUtils writes the gcp_utils.py shared by the modules and lookup
+// plugins (auth and request helpers), an existing file is kept unless
+// OverWrite is set
+func (td *TemplateData) GenerateModuleUtils(moduleUtils *ansible.ModuleUtils) error {
+	moduleUtilsFile := path.Join(td.ModuleUtilsDirectory, "gcp_utils.py")
+	if fileExists(moduleUtilsFile) && !td.OverWrite {
+		log.Info().Msgf("keeping existing module_utils file: %s", moduleUtilsFile)
+		return nil
+	}
+
+	if err := td.writeFile(moduleUtilsFile, "plugins/module_utils.tmpl", moduleUtils); err != nil {
+		return fmt.Errorf("error generating module_utils file: %v", err)
+	}
+
+	return nil
+}
+
+// GenerateGalaxy writes the galaxy.yml of the collection at the output root
+func (td *TemplateData) GenerateGalaxy(collection *ansible.Collection) error {
+	galaxyFile := path.Join(td.OutputFolder, "galaxy.yml")
+
+	if err := td.writeFile(galaxyFile, "galaxy.tmpl", collection); err != nil {
+		return fmt.Errorf("error generating galaxy file: %v", err)
+	}
+
+	return nil
+}
+
+// GenerateRuntime writes the meta/runtime.yml of the collection
+func (td *TemplateData) GenerateRuntime(runtime *ansible.Runtime) error {
+	runtimeFile := path.Join(td.OutputFolder, "meta", "runtime.yml")
+
+	if err := td.writeFile(runtimeFile, "runtime.tmpl", runtime); err != nil {
+		return fmt.Errorf("error generating runtime file: %v", err)
+	}
+
+	return nil
+}
+
+// INTEGRATION_TEST_FILES are the files of each module integration test target,
+// relative to the target directory, rendered from tests/integration/<file>.tmpl
+var INTEGRATION_TEST_FILES = []string{
+	"aliases",
+	"defaults/main.yml",
+	"meta/main.yml",
+	"tasks/autogen.yml",
+	"tasks/main.yml",
+}
+
+// GenerateTests writes the integration test target of the given module, the
+// files that already exist are kept unless OverWrite is set so re-running the
+// generation is idempotent
 func (td *TemplateData) GenerateTests(module *ansible.Module) error {
-	directories := []string{
-		td.IntegrationTestDirectory,
-		path.Join(td.IntegrationTestDirectory, module.Name),
-		path.Join(td.IntegrationTestDirectory, module.Name, "defaults"),
-		path.Join(td.IntegrationTestDirectory, module.Name, "meta"),
-		path.Join(td.IntegrationTestDirectory, module.Name, "tasks"),
-	}
-	for _, directory := range directories {
-		log.Debug().Msgf("creating integration test directory: %s", directory)
-		if err := os.MkdirAll(directory, 0755); err != nil {
-			return fmt.Errorf("error creating integration test directory: %v", err)
+	targetDirectory := path.Join(td.IntegrationTestDirectory, module.Name)
+
+	// fail before writing anything if a template is missing
+	for _, testFile := range INTEGRATION_TEST_FILES {
+		templateName := path.Join("tests", "integration", testFile+".tmpl")
+		if !fileExists(path.Join(td.TemplateDirectory, templateName)) {
+			return fmt.Errorf("missing integration test template: %s", path.Join(td.TemplateDirectory, templateName))
 		}
 	}
 
-	testFiles := []string{
-		path.Join(td.IntegrationTestDirectory, module.Name, "aliases"),
-		path.Join(td.IntegrationTestDirectory, module.Name, "defaults", "main.yml"),
-		path.Join(td.IntegrationTestDirectory, module.Name, "meta", "main.yml"),
-		path.Join(td.IntegrationTestDirectory, module.Name, "tasks", "autogen.yml"),
-	}
-	for _, testFile := range testFiles {
-		log.Debug().Msgf("creating integration test file: %s", testFile)
-		templateName := fmt.Sprintf("tests/integration/%s.tmpl", strings.TrimPrefix(testFile, filepath.Join(td.IntegrationTestDirectory, module.Name)))
-		if err := td.writeFile(testFile, templateName, module); err != nil {
-			return fmt.Errorf("error creating integration test file: %v", err)
+	for _, testFile := range INTEGRATION_TEST_FILES {
+		filePath := path.Join(targetDirectory, testFile)
+		if fileExists(filePath) && !td.OverWrite {
+			log.Info().Msgf("keeping existing integration test file: %s", filePath)
+			continue
 		}
+		log.Debug().Msgf("creating integration test file: %s", filePath)
+		if err := td.writeFile(filePath, path.Join("tests", "integration", testFile+".tmpl"), module); err != nil {
+			return fmt.Errorf("error creating integration test file %s: %v", filePath, err)
+		}
+	}
+
+	return nil
+}
+
+// GenerateUnitTests writes the argument spec unit test of the given module
+// (test_<module>.py), an existing file is kept unless OverWrite is set
+func (td *TemplateData) GenerateUnitTests(module *ansible.Module) error {
+	testFile := path.Join(td.UnitTestDirectory, fmt.Sprintf("test_%s.py", module))
+	if fileExists(testFile) && !td.OverWrite {
+		log.Info().Msgf("keeping existing unit test file: %s", testFile)
+		return nil
+	}
+
+	if err := td.writeFile(testFile, "tests/unit/test_module.py.tmpl", module); err != nil {
+		return fmt.Errorf("error generating unit test file: %v", err)
 	}
 
 	return nil
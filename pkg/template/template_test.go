@@ -0,0 +1,1439 @@
+// Copyright 2025 Red Hat Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/thekad/magic-ansible/pkg/ansible"
+	"github.com/thekad/magic-ansible/pkg/api"
+	"gopkg.in/yaml.v3"
+)
+
+// TEST_TEMPLATE_DIR is the repository template directory, relative to this package
+const TEST_TEMPLATE_DIR = "../../templates"
+
+// TEST_PYTHON_DIR holds the stubs of the ansible and requests libraries the
+// generated code imports, and the harness running a module against a fake API
+const TEST_PYTHON_DIR = "testdata/python"
+
+const testProductYAML = `name: Widgets
+display_name: Widgets
+versions:
+  - name: ga
+    base_url: https://widgets.googleapis.com/v1/
+scopes:
+  - https://www.googleapis.com/auth/cloud-platform
+`
+
+const testResourceYAML = `name: Widget
+base_url: projects/{{project}}/locations/{{location}}/widgets
+self_link: projects/{{project}}/locations/{{location}}/widgets/{{name}}
+create_url: projects/{{project}}/locations/{{location}}/widgets?widgetId={{name}}
+update_verb: PATCH
+parameters:
+  - name: project
+    type: String
+    description: The project.
+    url_param_only: true
+    required: true
+  - name: location
+    type: String
+    description: The location.
+    url_param_only: true
+    required: true
+  - name: name
+    type: String
+    description: The widget name.
+    url_param_only: true
+    required: true
+properties:
+  - name: displayName
+    type: String
+    description: The display name.
+    required: true
+  - name: size
+    type: String
+    description: The widget size.
+  - name: createTime
+    type: String
+    description: The creation time.
+    output: true
+`
+
+// testWidgetLink is the (read, update and delete) link of the test widget
+const testWidgetLink = "https://widgets.googleapis.com/v1/projects/p/locations/l/widgets/w"
+
+// testWidgetArgs are the module arguments of the test widget
+func testWidgetArgs() map[string]any {
+	return map[string]any{
+		"project":      "p",
+		"location":     "l",
+		"name":         "w",
+		"display_name": "My widget",
+		"auth_kind":    "application",
+		"state":        "present",
+	}
+}
+
+// newTestModule writes the given product.yaml and resource files (by name,
+// without the .yaml suffix) in a temporary products directory and returns the
+// module of the named resource
+func newTestModule(t *testing.T, productYAML string, resources map[string]string, name string, config *ansible.Config) *ansible.Module {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "products", "widgets")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	productFile := filepath.Join(dir, "product.yaml")
+	if err := os.WriteFile(productFile, []byte(productYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for resourceName, resourceYAML := range resources {
+		if err := os.WriteFile(filepath.Join(dir, resourceName+".yaml"), []byte(resourceYAML), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	product := api.NewProduct(productFile, TEST_TEMPLATE_DIR, t.TempDir())
+	if err := product.Unmarshal(); err != nil {
+		t.Fatal(err)
+	}
+	resource := api.NewResource(filepath.Join(dir, name+".yaml"), product, TEST_TEMPLATE_DIR, t.TempDir())
+	if err := resource.Unmarshal(); err != nil {
+		t.Fatal(err)
+	}
+
+	return ansible.NewFromResource(resource, config)
+}
+
+// renderCollection writes gcp_utils.py and the given modules in a temporary
+// ansible_collections/google/cloud layout and returns its root, the test is
+// skipped when python3 isn't available
+func renderCollection(t *testing.T, modules ...*ansible.Module) string {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not available")
+	}
+	root := t.TempDir()
+	td := NewTemplateData(TEST_TEMPLATE_DIR, filepath.Join(root, "ansible_collections", "google", "cloud"), "", "", true)
+	if err := td.GenerateModuleUtils(ansible.NewModuleUtils(ansible.NewConfig())); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range modules {
+		if err := td.GenerateCode(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// renderLookup writes the lookup plugin of the product of the given modules in
+// the collection rendered by renderCollection
+func renderLookup(t *testing.T, root string, modules ...*ansible.Module) *ansible.Lookup {
+	t.Helper()
+	lookup := ansible.NewLookupFromModules(modules[0].Resource.Parent, modules, ansible.NewConfig())
+	td := NewTemplateData(TEST_TEMPLATE_DIR, filepath.Join(root, "ansible_collections", "google", "cloud"), "", "", true)
+	if err := td.GenerateLookup(lookup); err != nil {
+		t.Fatal(err)
+	}
+	return lookup
+}
+
+// runPython runs the given python code with the collection and the stubs in
+// the path, the input is sent as JSON on stdin and the JSON output decoded
+// in output
+func runPython(t *testing.T, root string, code string, input any, output any) {
+	t.Helper()
+	stubs, err := filepath.Abs(TEST_PYTHON_DIR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("python3", "-c", code)
+	cmd.Env = append(os.Environ(), "PYTHONPATH="+strings.Join([]string{root, stubs}, string(os.PathListSeparator)), "PYTHONDONTWRITEBYTECODE=1")
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("python failed: %v\n%s", err, stderr.String())
+	}
+	if err := json.Unmarshal(stdout, output); err != nil {
+		t.Fatalf("invalid python output %q: %v", stdout, err)
+	}
+}
+
+// fakeResponse is an API response of the harness, the first one matching the
+// method and (a part of) the URL of a call is used, Times times if set
+type fakeResponse struct {
+	Method string `json:"method,omitempty"`
+	Url    string `json:"url,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Body   any    `json:"body,omitempty"`
+	Times  *int   `json:"times,omitempty"`
+}
+
+// once returns a pointer to 1, for the fakeResponse used once
+func once() *int {
+	n := 1
+	return &n
+}
+
+// moduleRun is a run of a generated module (or lookup plugin, with its terms)
+// by the harness
+type moduleRun struct {
+	Module    string         `json:"module,omitempty"`
+	Lookup    string         `json:"lookup,omitempty"`
+	Terms     []string       `json:"terms,omitempty"`
+	Args      map[string]any `json:"args"`
+	Responses []fakeResponse `json:"responses"`
+	CheckMode bool           `json:"check_mode,omitempty"`
+	Diff      bool           `json:"diff,omitempty"`
+	Verbosity int            `json:"verbosity,omitempty"`
+}
+
+type apiCall struct {
+	Method  string            `json:"method"`
+	Url     string            `json:"url"`
+	Body    map[string]any    `json:"body"`
+	Headers map[string]string `json:"headers"`
+	Timeout float64           `json:"timeout"`
+}
+
+// moduleResult is what the harness reports of a module run
+type moduleResult struct {
+	Failed bool           `json:"failed"`
+	Result map[string]any `json:"result"`
+	Calls  []apiCall      `json:"calls"`
+	Debug  []string       `json:"debug"`
+	Log    []string       `json:"log"`
+}
+
+// methods returns the "METHOD url" of the API calls
+func (r moduleResult) methods() []string {
+	calls := []string{}
+	for _, call := range r.Calls {
+		calls = append(calls, call.Method+" "+call.Url)
+	}
+	return calls
+}
+
+// runModule runs the generated module against the fake API responses
+func runModule(t *testing.T, root string, run moduleRun) moduleResult {
+	t.Helper()
+	harness, err := os.ReadFile(filepath.Join(TEST_PYTHON_DIR, "harness.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := moduleResult{}
+	runPython(t, root, string(harness), run, &result)
+	return result
+}
+
+func TestDiffers(t *testing.T) {
+	root := renderCollection(t)
+	tests := []struct {
+		name    string
+		wanted  any
+		actual  any
+		ignored []string
+		want    bool
+	}{
+		{"same", map[string]any{"labels": map[string]any{"a": "1"}}, map[string]any{"labels": map[string]any{"a": "1"}}, nil, false},
+		{"key added", map[string]any{"labels": map[string]any{"a": "1", "b": "2"}}, map[string]any{"labels": map[string]any{"a": "1"}}, nil, true},
+		{"key removed", map[string]any{"labels": map[string]any{"a": "1"}}, map[string]any{"labels": map[string]any{"a": "1", "b": "2"}}, nil, false},
+		{"key changed", map[string]any{"labels": map[string]any{"a": "1"}}, map[string]any{"labels": map[string]any{"a": "2"}}, nil, true},
+		{"labels unset", map[string]any{"labels": map[string]any{"a": "1"}}, map[string]any{}, nil, true},
+		{"top-level write only", map[string]any{"password": "secret"}, map[string]any{}, nil, false},
+		{"nested default omitted", map[string]any{"config": map[string]any{"enabled": false}}, map[string]any{"config": map[string]any{}}, nil, false},
+		{"ignored in unset", map[string]any{"user": map[string]any{"password": "secret"}}, map[string]any{}, []string{"user.password"}, false},
+		{"ignored nested", map[string]any{"user": map[string]any{"password": "secret"}}, map[string]any{"user": map[string]any{}}, []string{"user.password"}, false},
+		{"nested list item added", map[string]any{"rules": []any{map[string]any{"port": 80}}}, map[string]any{"rules": []any{map[string]any{}}}, nil, true},
+		{"64 bit integer", map[string]any{"size": 10}, map[string]any{"size": "10"}, nil, false},
+	}
+
+	code := `import json, sys
+from ansible_collections.google.cloud.plugins.module_utils import gcp_utils as gcp
+args = json.load(sys.stdin)
+json.dump(gcp._differs(args["wanted"], args["actual"], args["ignored"]), sys.stdout)`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bool
+			runPython(t, root, code, map[string]any{"wanted": tt.wanted, "actual": tt.actual, "ignored": tt.ignored}, &got)
+			if got != tt.want {
+				t.Errorf("_differs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceOpConfigs(t *testing.T) {
+	root := renderCollection(t)
+	code := `import json, sys
+from ansible_collections.google.cloud.plugins.module_utils import gcp_utils as gcp
+configs = gcp.ResourceOpConfigs({"update": gcp.ResourceOpConfig("u", verb="PATCH"), "read": gcp.ResourceOpConfig("r")})
+json.dump([configs.update.verb, configs.read.uri], sys.stdout)`
+	var got []string
+	runPython(t, root, code, nil, &got)
+	if !slices.Equal(got, []string{"patch", "r"}) {
+		t.Errorf("operations = %v, want [patch r]", got)
+	}
+}
+
+// testAsyncYAML makes the test widget a long running operation (OpAsync) resource
+const testAsyncYAML = `async:
+  type: OpAsync
+  actions: ['create', 'delete', 'update']
+  operation:
+    base_url: '{{op_id}}'
+`
+
+func TestRollback(t *testing.T) {
+	widgetYAML := strings.Replace(testResourceYAML, "parameters:", testAsyncYAML+"rollback_on_failure: true\nparameters:", 1)
+	tests := []struct {
+		name         string
+		resourceYAML string
+		responses    []fakeResponse
+		failed       bool
+		rolledBack   bool
+	}{
+		{
+			name:         "conflict is idempotent",
+			resourceYAML: widgetYAML,
+			responses:    []fakeResponse{{Method: "POST", Status: 409, Body: map[string]any{"error": map[string]any{"message": "already exists"}}}},
+		},
+		{
+			name:         "conflict",
+			resourceYAML: strings.Replace(widgetYAML, "parameters:", "conflict_is_idempotent: false\nparameters:", 1),
+			responses:    []fakeResponse{{Method: "POST", Status: 409, Body: map[string]any{"error": map[string]any{"message": "already exists"}}}},
+			failed:       true,
+		},
+		{
+			name:         "create call failed",
+			resourceYAML: widgetYAML,
+			responses:    []fakeResponse{{Method: "POST", Status: 400, Body: map[string]any{"error": map[string]any{"message": "bad request"}}}},
+			failed:       true,
+		},
+		{
+			name:         "operation failed",
+			resourceYAML: widgetYAML,
+			responses: []fakeResponse{
+				{Method: "POST", Body: map[string]any{"name": "operations/create", "done": false}},
+				{Url: "operations/create", Body: map[string]any{"name": "operations/create", "done": true, "error": map[string]any{"message": "quota exceeded"}}},
+				{Method: "DELETE", Body: map[string]any{"name": "operations/delete", "done": true}},
+			},
+			failed:     true,
+			rolledBack: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resourceYAML}, "Widget", nil)
+			root := renderCollection(t, m)
+			// not found when first looked up, there after the create
+			responses := append([]fakeResponse{{Url: testWidgetLink, Status: 404, Times: once()}}, tt.responses...)
+			responses = append(responses, fakeResponse{Url: testWidgetLink, Body: map[string]any{"displayName": "My widget"}})
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+			if got.Failed != tt.failed {
+				t.Fatalf("failed = %v, want %v: %v", got.Failed, tt.failed, got.Result)
+			}
+			deleted := slices.ContainsFunc(got.Calls, func(c apiCall) bool { return c.Method == "DELETE" })
+			if deleted != tt.rolledBack {
+				t.Errorf("rolled back = %v, want %v: %v", deleted, tt.rolledBack, got.methods())
+			}
+		})
+	}
+}
+
+func TestLabelDiff(t *testing.T) {
+	widgetYAML := testResourceYAML + `  - name: labels
+    type: KeyValueLabels
+    description: The labels.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	args["labels"] = map[string]any{"env": "prod", "team": "infra"}
+	responses := []fakeResponse{
+		{Url: testWidgetLink, Body: map[string]any{"displayName": "My widget", "labels": map[string]any{"env": "prod"}}, Times: once()},
+		{Method: "PATCH", Body: map[string]any{}},
+		{Url: testWidgetLink, Body: map[string]any{"displayName": "My widget", "labels": map[string]any{"env": "prod", "team": "infra"}}},
+	}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses, Diff: true})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	if got.Result["changed"] != true {
+		t.Errorf("changed = %v, want true", got.Result["changed"])
+	}
+	if !slices.ContainsFunc(got.Calls, func(c apiCall) bool { return c.Method == "PATCH" }) {
+		t.Errorf("the added label wasn't sent: %v", got.methods())
+	}
+	want := map[string]any{
+		"before": map[string]any{"labels": map[string]any{}},
+		"after":  map[string]any{"labels": map[string]any{"team": "infra"}},
+	}
+	if diff, _ := json.Marshal(got.Result["diff"]); string(diff) != mustJSON(t, want) {
+		t.Errorf("diff = %s, want %s", diff, mustJSON(t, want))
+	}
+}
+
+// mustJSON returns the JSON encoding of value
+func mustJSON(t *testing.T, value any) string {
+	t.Helper()
+	b, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUpdateMask(t *testing.T) {
+	widgetYAML := strings.Replace(testResourceYAML, "update_verb: PATCH", "update_verb: PATCH\nupdate_mask: true", 1) + `  - name: labels
+    type: KeyValueLabels
+    description: The labels.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	tests := []struct {
+		name     string
+		existing map[string]any
+		want     string
+	}{
+		{"label added", map[string]any{"displayName": "My widget", "labels": map[string]any{"env": "prod"}}, "labels"},
+		{"label changed", map[string]any{"displayName": "My widget", "labels": map[string]any{"env": "dev", "team": "infra"}}, "labels"},
+		{"labels unset", map[string]any{"displayName": "My widget"}, "labels"},
+		{"field changed", map[string]any{"displayName": "Old widget", "labels": map[string]any{"env": "prod", "team": "infra"}}, "displayName"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := testWidgetArgs()
+			args["labels"] = map[string]any{"env": "prod", "team": "infra"}
+			responses := []fakeResponse{
+				{Url: testWidgetLink, Body: tt.existing, Times: once()},
+				{Method: "PATCH", Body: map[string]any{}},
+				{Url: testWidgetLink, Body: tt.existing},
+			}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+			if got.Failed {
+				t.Fatalf("module failed: %v", got.Result)
+			}
+			i := slices.IndexFunc(got.Calls, func(c apiCall) bool { return c.Method == "PATCH" })
+			if i < 0 {
+				t.Fatalf("no update: %v", got.methods())
+			}
+			if want := "updateMask=" + tt.want; !strings.HasSuffix(got.Calls[i].Url, want) {
+				t.Errorf("update link = %s, want the %s suffix", got.Calls[i].Url, want)
+			}
+		})
+	}
+}
+
+func TestIdentityLookup(t *testing.T) {
+	widgetYAML := strings.Replace(testResourceYAML, "parameters:", "identity:\n  - region\n  - name\nparameters:", 1) + `  - name: region
+    type: String
+    description: The region.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	listed := map[string]any{"widgets": []any{
+		map[string]any{"name": "projects/p/locations/l/widgets/w", "region": "us-east1", "displayName": "My widget"},
+		map[string]any{"name": "projects/p/locations/l/widgets/other", "region": "us-west1", "displayName": "Other widget"},
+	}}
+	tests := []struct {
+		region      string
+		wantCreated bool
+	}{
+		{"us-east1", false},
+		{"us-west1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			args := testWidgetArgs()
+			args["region"] = tt.region
+			responses := []fakeResponse{
+				{Url: testWidgetLink, Body: map[string]any{"displayName": "My widget", "region": tt.region}},
+				{Url: "/widgets", Body: listed},
+				{Method: "POST", Body: map[string]any{}},
+			}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+			if got.Failed {
+				t.Fatalf("module failed: %v", got.Result)
+			}
+			if got.Calls[0].Url != "https://widgets.googleapis.com/v1/projects/p/locations/l/widgets" {
+				t.Errorf("existence lookup = %s, want the list link", got.Calls[0].Url)
+			}
+			created := slices.ContainsFunc(got.Calls, func(c apiCall) bool { return c.Method == "POST" })
+			if created != tt.wantCreated {
+				t.Errorf("created = %v, want %v: %v", created, tt.wantCreated, got.methods())
+			}
+		})
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	widgetYAML := testResourceYAML + `  - name: labels
+    type: KeyValueLabels
+    description: The labels.
+  - name: tags
+    type: Array
+    description: The tags.
+    item_type:
+      type: String
+  - name: zones
+    type: Array
+    description: The zones.
+    item_type:
+      type: String
+    merge_strategy: append
+  - name: config
+    type: NestedObject
+    description: The configuration.
+    properties:
+      - name: mode
+        type: String
+        description: The mode.
+      - name: size
+        type: Integer
+        description: The size.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	existing := map[string]any{
+		"displayName": "My widget",
+		"labels":      map[string]any{"env": "prod"},
+		"tags":        []any{"a"},
+		"zones":       []any{"z1"},
+		"config":      map[string]any{"mode": "FAST", "size": 1},
+	}
+	args := testWidgetArgs()
+	args["labels"] = map[string]any{"team": "infra"}
+	args["tags"] = []any{"b"}
+	args["zones"] = []any{"z2"}
+	args["config"] = map[string]any{"size": 2}
+	responses := []fakeResponse{
+		{Url: testWidgetLink, Body: existing},
+		{Method: "PATCH", Body: map[string]any{}},
+	}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	i := slices.IndexFunc(got.Calls, func(c apiCall) bool { return c.Method == "PATCH" })
+	if i < 0 {
+		t.Fatalf("no update: %v", got.methods())
+	}
+	body := got.Calls[i].Body
+	for field, want := range map[string]any{
+		"labels": map[string]any{"env": "prod", "team": "infra"}, // merge (default)
+		"tags":   []any{"b"},                                     // replace (default)
+		"zones":  []any{"z1", "z2"},                              // append
+		"config": map[string]any{"mode": "FAST", "size": 2},      // merge (default)
+	} {
+		if mustJSON(t, body[field]) != mustJSON(t, want) {
+			t.Errorf("%s = %s, want %s", field, mustJSON(t, body[field]), mustJSON(t, want))
+		}
+	}
+
+	// nothing to append nor merge is no change
+	args["zones"] = []any{"z1"}
+	args["tags"] = []any{"a"}
+	args["labels"] = map[string]any{"env": "prod"}
+	args["config"] = map[string]any{"size": 1}
+	got = runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if got.Failed || got.Result["changed"] != false {
+		t.Errorf("result = %v, want unchanged: %v", got.Result, got.methods())
+	}
+}
+
+func TestPyVarName(t *testing.T) {
+	gadgetYAML := `name: Gadget
+base_url: projects/{{project}}/gadgets
+self_link: projects/{{project}}/gadgets/{{name}}
+properties:
+  - name: name
+    type: String
+    description: The gadget name.
+`
+	widgetYAML := strings.Replace(testResourceYAML, "{{location}}", "{{type}}", -1) + `  - name: type
+    type: ResourceRef
+    description: The gadget type of the widget.
+    resource: Gadget
+    imports: name
+    url_param_only: true
+    required: true
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML, "Gadget": gadgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	code, err := os.ReadFile(filepath.Join(root, "ansible_collections", "google", "cloud", DEFAULT_MODULE_PATH, m.Name+".py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(code), "\n    type_ = gcp.resource_ref(") || strings.Contains(string(code), "\n    type = ") {
+		t.Errorf("the type reference shadows the builtin:\n%s", code)
+	}
+
+	args := testWidgetArgs()
+	delete(args, "location")
+	args["type"] = map[string]any{"project": "p", "name": "g"}
+	responses := []fakeResponse{{Url: "/widgets/w", Body: map[string]any{"displayName": "My widget"}}}
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	if !strings.Contains(got.Calls[0].Url, "projects/p/gadgets/g") {
+		t.Errorf("read link = %s, want the gadget link in it", got.Calls[0].Url)
+	}
+}
+
+func TestResourceRefComponents(t *testing.T) {
+	gadgetYAML := `name: Gadget
+base_url: projects/{{project}}/locations/{{location}}/gadgets
+self_link: projects/{{project}}/locations/{{location}}/gadgets/{{gadget_id}}
+properties:
+  - name: name
+    type: String
+    description: The gadget name.
+    output: true
+`
+	widgetYAML := testResourceYAML + `  - name: gadget
+    type: ResourceRef
+    description: The gadget of the widget.
+    resource: Gadget
+    imports: name
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML, "Gadget": gadgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	tests := []struct {
+		name    string
+		gadget  map[string]any
+		wantRef string
+		wantMsg string
+	}{
+		{"components", map[string]any{"project": "gp", "location": "gl", "gadget_id": "g"}, "projects/gp/locations/gl/gadgets/g", ""},
+		{"self link", map[string]any{"self_link": "projects/x/locations/y/gadgets/z"}, "projects/x/locations/y/gadgets/z", ""},
+		// the components don't fall back to the module options
+		{"partial", map[string]any{"gadget_id": "g"}, "", "missing 'project'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := testWidgetArgs()
+			args["gadget"] = tt.gadget
+			responses := []fakeResponse{
+				{Url: testWidgetLink, Status: 404, Times: once()},
+				{Method: "POST", Body: map[string]any{}},
+				{Url: testWidgetLink, Body: map[string]any{"name": "w", "displayName": "My widget", "gadget": tt.wantRef}},
+			}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+			if tt.wantMsg != "" {
+				if msg, _ := got.Result["msg"].(string); !got.Failed || !strings.Contains(msg, tt.wantMsg) {
+					t.Errorf("result = %v, want a failure with %s", got.Result, tt.wantMsg)
+				}
+				return
+			}
+			if got.Failed {
+				t.Fatalf("module failed: %v", got.Result)
+			}
+			if !slices.ContainsFunc(got.methods(), func(call string) bool { return strings.HasPrefix(call, "POST ") }) {
+				t.Fatalf("calls = %v, want a create", got.methods())
+			}
+			for _, call := range got.Calls {
+				if call.Method == "POST" && call.Body["gadget"] != tt.wantRef {
+					t.Errorf("POST gadget = %v, want %s", call.Body["gadget"], tt.wantRef)
+				}
+			}
+		})
+	}
+}
+
+func TestMutuallyExclusiveChecks(t *testing.T) {
+	widgetYAML := strings.Replace(testResourceYAML, `    description: The widget size.
+`, `    description: The widget size.
+    conflicts:
+      - tier
+`, 1) + `  - name: tier
+    type: Enum
+    description: The widget tier.
+    enum_values:
+      - BASIC
+      - PREMIUM
+    default: BASIC
+`
+	config := ansible.NewConfig()
+	config.ExplainConflicts = true
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", config)
+	root := renderCollection(t, m)
+	tests := []struct {
+		name       string
+		args       map[string]any
+		wantFailed bool
+	}{
+		{"default", map[string]any{"size": "L"}, false},
+		{"given", map[string]any{"size": "L", "tier": "PREMIUM"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := testWidgetArgs()
+			maps.Copy(args, tt.args)
+			responses := []fakeResponse{{Url: testWidgetLink, Body: map[string]any{"displayName": "My widget", "size": "L", "tier": "BASIC"}}}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+			if got.Failed != tt.wantFailed {
+				t.Fatalf("failed = %v, want %v: %v", got.Failed, tt.wantFailed, got.Result)
+			}
+			if msg, _ := got.Result["msg"].(string); tt.wantFailed && !strings.Contains(msg, "size, tier are mutually exclusive") {
+				t.Errorf("msg = %q, want the descriptive one", msg)
+			}
+		})
+	}
+}
+
+func TestRegionalEndpoint(t *testing.T) {
+	productYAML := strings.Replace(testProductYAML, "https://widgets.googleapis.com/v1/", "https://{{region}}-widgets.googleapis.com/v1/", 1)
+	m := newTestModule(t, productYAML, map[string]string{"Widget": testResourceYAML}, "Widget", ansible.NewConfig())
+	root := renderCollection(t, m)
+	lookup := renderLookup(t, root, m)
+	regionalLink := "https://l-widgets.googleapis.com/v1/projects/p/locations/l/widgets/w"
+	responses := []fakeResponse{{Url: regionalLink, Body: map[string]any{"name": "w", "displayName": "My widget"}}}
+
+	t.Run("module", func(t *testing.T) {
+		got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+		if got.Failed {
+			t.Fatalf("module failed: %v", got.Result)
+		}
+		for _, call := range got.Calls {
+			if call.Url != regionalLink {
+				t.Errorf("call to %s, want the regional link", call.Url)
+			}
+		}
+	})
+
+	tests := []struct {
+		name    string
+		params  map[string]any
+		wantMsg string
+	}{
+		{"lookup", map[string]any{"project": "p", "location": "l", "name": "w"}, ""},
+		{"lookup without region", map[string]any{"project": "p"}, "API endpoint is regional"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]any{"params": tt.params, "auth_kind": "application"}
+			got := runModule(t, root, moduleRun{Lookup: lookup.Name, Terms: []string{"widget"}, Args: args, Responses: responses})
+			if tt.wantMsg != "" {
+				if msg, _ := got.Result["msg"].(string); !got.Failed || !strings.Contains(msg, tt.wantMsg) {
+					t.Errorf("result = %v, want a failure with %q", got.Result, tt.wantMsg)
+				}
+				return
+			}
+			if got.Failed {
+				t.Fatalf("lookup failed: %v", got.Result)
+			}
+			if calls := got.methods(); !slices.Equal(calls, []string{"GET " + regionalLink}) {
+				t.Errorf("calls = %v, want the regional read", calls)
+			}
+		})
+	}
+}
+
+func TestLookupItemsKey(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": "collection_url_key: resources\n" + testResourceYAML}, "Widget", ansible.NewConfig())
+	root := renderCollection(t, m)
+	lookup := renderLookup(t, root, m)
+	items := []any{map[string]any{"name": "a"}, map[string]any{"name": "b"}}
+	responses := []fakeResponse{{Url: "/widgets", Body: map[string]any{"resources": items, "widgets": []any{map[string]any{"name": "wrong"}}}}}
+	args := map[string]any{"params": map[string]any{"project": "p", "location": "l"}, "auth_kind": "application"}
+
+	got := runModule(t, root, moduleRun{Lookup: lookup.Name, Terms: []string{"widget"}, Args: args, Responses: responses})
+	if got.Failed {
+		t.Fatalf("lookup failed: %v", got.Result)
+	}
+	if raw := mustJSON(t, got.Result["raw"]); raw != mustJSON(t, items) {
+		t.Errorf("raw = %s, want the resources items", raw)
+	}
+}
+
+func TestLogCall(t *testing.T) {
+	widgetYAML := testResourceYAML + `  - name: auth
+    type: NestedObject
+    description: The widget credentials.
+    properties:
+      - name: password
+        type: String
+        description: The widget password.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	args["auth"] = map[string]any{"password": "hunter2"}
+	created := map[string]any{"name": "w", "displayName": "My widget", "auth": map[string]any{"password": "hunter2"}}
+
+	for _, verbosity := range []int{0, 3} {
+		t.Run(fmt.Sprintf("verbosity %d", verbosity), func(t *testing.T) {
+			responses := []fakeResponse{
+				{Url: testWidgetLink, Status: 404, Times: once()},
+				{Method: "POST", Body: created},
+				{Url: testWidgetLink, Body: created},
+			}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses, Verbosity: verbosity})
+			if got.Failed {
+				t.Fatalf("module failed: %v", got.Result)
+			}
+			if len(got.Log) > 0 {
+				t.Errorf("calls logged to the system log: %v", got.Log)
+			}
+			if verbosity == 0 {
+				if len(got.Debug) > 0 {
+					t.Errorf("calls logged below the log verbosity: %v", got.Debug)
+				}
+				return
+			}
+			post := slices.IndexFunc(got.Debug, func(msg string) bool { return strings.HasPrefix(msg, "POST ") })
+			if post < 0 {
+				t.Fatalf("no POST call in the debug messages: %v", got.Debug)
+			}
+			if msg := got.Debug[post]; strings.Contains(msg, "hunter2") || !strings.Contains(msg, `"password": "********"`) {
+				t.Errorf("the password isn't redacted: %s", msg)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", ansible.NewConfig())
+	root := renderCollection(t, m)
+	lookup := renderLookup(t, root, m)
+	listLink := "https://widgets.googleapis.com/v1/projects/p/locations/l/widgets"
+
+	code, err := os.ReadFile(filepath.Join(root, "ansible_collections", "google", "cloud", "plugins", "lookup", "gcp_widgets.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"list_uri":"projects/{project}/locations/{location}/widgets"`; !strings.Contains(string(code), want) {
+		t.Errorf("want %s in the lookup plugin", want)
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]any
+		responses []fakeResponse
+		wantCalls []string
+		wantRaw   []any
+	}{
+		{
+			name:   "list",
+			params: map[string]any{"project": "p", "location": "l"},
+			responses: []fakeResponse{
+				{Url: "pageToken=next", Body: map[string]any{"widgets": []any{map[string]any{"name": "b"}}}},
+				{Url: listLink, Body: map[string]any{"widgets": []any{map[string]any{"name": "a"}}, "nextPageToken": "next"}},
+			},
+			wantCalls: []string{"GET " + listLink, "GET " + listLink + "?pageToken=next"},
+			wantRaw:   []any{map[string]any{"name": "a"}, map[string]any{"name": "b"}},
+		},
+		{
+			name:      "read",
+			params:    map[string]any{"project": "p", "location": "l", "name": "w"},
+			responses: []fakeResponse{{Url: testWidgetLink, Body: map[string]any{"name": "w"}}},
+			wantCalls: []string{"GET " + testWidgetLink},
+			wantRaw:   []any{map[string]any{"name": "w"}},
+		},
+		{
+			name:      "read not found",
+			params:    map[string]any{"project": "p", "location": "l", "name": "w"},
+			responses: []fakeResponse{{Url: testWidgetLink, Status: 404}},
+			wantCalls: []string{"GET " + testWidgetLink},
+			wantRaw:   []any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]any{"params": tt.params, "auth_kind": "application"}
+			got := runModule(t, root, moduleRun{Lookup: lookup.Name, Terms: []string{"widget"}, Args: args, Responses: tt.responses})
+			if got.Failed {
+				t.Fatalf("lookup failed: %v", got.Result)
+			}
+			if calls := got.methods(); !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if raw := mustJSON(t, got.Result["raw"]); raw != mustJSON(t, tt.wantRaw) {
+				t.Errorf("raw = %s, want %s", raw, mustJSON(t, tt.wantRaw))
+			}
+		})
+	}
+}
+
+func TestConflictIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceYAML string
+		wantFailed   bool
+	}{
+		{"default", testResourceYAML, false},
+		{"override", "conflict_is_idempotent: false\n" + testResourceYAML, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resourceYAML}, "Widget", nil)
+			root := renderCollection(t, m)
+			responses := []fakeResponse{
+				{Url: testWidgetLink, Status: 404},
+				{Method: "POST", Status: 409, Body: map[string]any{"error": map[string]any{"message": "already exists"}}},
+			}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+			if got.Failed != tt.wantFailed {
+				t.Fatalf("failed = %v, want %v: %v", got.Failed, tt.wantFailed, got.Result)
+			}
+			if got.Result["changed"] == true {
+				t.Errorf("changed on a conflict: %v", got.Result)
+			}
+		})
+	}
+}
+
+func TestForceDelete(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceYAML string
+		force        bool
+		wantDeleted  bool
+	}{
+		{"not dangerous", testResourceYAML, false, true},
+		{"dangerous", "dangerous_delete: true\n" + testResourceYAML, false, false},
+		{"dangerous forced", "dangerous_delete: true\n" + testResourceYAML, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resourceYAML}, "Widget", nil)
+			if got, want := m.RequiresForceDelete(), strings.HasPrefix(tt.resourceYAML, "dangerous_delete"); got != want {
+				t.Errorf("RequiresForceDelete() = %v, want %v", got, want)
+			}
+			root := renderCollection(t, m)
+			args := testWidgetArgs()
+			args["state"] = "absent"
+			args["force"] = tt.force
+			responses := []fakeResponse{
+				{Url: testWidgetLink, Body: map[string]any{"name": "w", "displayName": "My widget"}, Times: once()},
+				{Method: "DELETE", Url: testWidgetLink, Body: map[string]any{}},
+				{Url: testWidgetLink, Status: 404},
+			}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+			if got.Failed == tt.wantDeleted {
+				t.Fatalf("failed = %v: %v", got.Failed, got.Result)
+			}
+			if deleted := slices.Contains(got.methods(), "DELETE "+testWidgetLink); deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v: %v", deleted, tt.wantDeleted, got.methods())
+			}
+			if msg, _ := got.Result["msg"].(string); !tt.wantDeleted && !strings.Contains(msg, "set force=true") {
+				t.Errorf("msg = %q, want the force hint", msg)
+			}
+		})
+	}
+}
+
+func TestCreateQueryParams(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	// the query values are URL encoded, the path ones aren't
+	args["name"] = "w&x"
+	link := "https://widgets.googleapis.com/v1/projects/p/locations/l/widgets"
+	responses := []fakeResponse{
+		{Url: link + "/w&x", Status: 404, Times: once()},
+		{Method: "POST", Body: map[string]any{"name": "w&x", "displayName": "My widget"}},
+		{Url: link + "/w&x", Body: map[string]any{"name": "w&x", "displayName": "My widget"}},
+	}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	if want := "POST " + link + "?widgetId=w%26x"; !slices.Contains(got.methods(), want) {
+		t.Errorf("calls = %v, want %s", got.methods(), want)
+	}
+}
+
+func TestIntegrationTasksMain(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	root := t.TempDir()
+	td := NewTemplateData(TEST_TEMPLATE_DIR, root, "", "", false)
+	if err := td.GenerateTests(m); err != nil {
+		t.Fatal(err)
+	}
+	tasksDir := filepath.Join(td.IntegrationTestDirectory, m.Name, "tasks")
+	content, err := os.ReadFile(filepath.Join(tasksDir, "main.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := []map[string]any{}
+	if err := yaml.Unmarshal(content, &tasks); err != nil {
+		t.Fatalf("main.yml isn't valid YAML: %v\n%s", err, content)
+	}
+	includes := []any{}
+	for _, task := range tasks {
+		includes = append(includes, task["ansible.builtin.include_tasks"])
+	}
+	if len(includes) != 3 || includes[1] != "autogen.yml" {
+		t.Errorf("main.yml includes %v, want autogen.yml between the custom tasks", includes)
+	}
+	for i, custom := range []string{"pre.yml", "post.yml"} {
+		if got := mustJSON(t, tasks[i*2]["with_first_found"]); !strings.Contains(got, `"`+custom+`"`) || !strings.Contains(got, `"skip":true`) {
+			t.Errorf("task %d = %s, want an optional %s", i*2, got, custom)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tasksDir, "autogen.yml")); err != nil {
+		t.Errorf("autogen.yml wasn't generated: %v", err)
+	}
+
+	// a hand-written main.yml is kept
+	if err := os.WriteFile(filepath.Join(tasksDir, "main.yml"), []byte("---\n[]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.GenerateTests(m); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tasksDir, "main.yml")); string(content) != "---\n[]\n" {
+		t.Errorf("the existing main.yml was overwritten:\n%s", content)
+	}
+}
+
+func TestReturnsIncludeResult(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": "returns_include:\n  - createTime\n" + testResourceYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	responses := []fakeResponse{{Url: testWidgetLink, Body: map[string]any{"name": "w", "displayName": "My widget", "createTime": "2025-01-01T00:00:00Z"}}}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	if keys := slices.Sorted(maps.Keys(got.Result)); !slices.Equal(keys, []string{"changed", "create_time"}) {
+		t.Errorf("result keys = %v, want create_time and changed only", keys)
+	}
+}
+
+func TestUserAgentHeader(t *testing.T) {
+	config := ansible.NewConfig()
+	config.Collection = "acme.cloud"
+	config.CollectionVersion = "1.2.3"
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", config)
+	root := renderCollection(t, m)
+	responses := []fakeResponse{{Url: testWidgetLink, Body: map[string]any{"name": "w", "displayName": "My widget"}}}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+	if got.Failed || len(got.Calls) == 0 {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	for _, call := range got.Calls {
+		if ua := call.Headers["User-Agent"]; ua != "ansible-acme.cloud/1.2.3" {
+			t.Errorf("%s %s User-Agent = %q, want ansible-acme.cloud/1.2.3", call.Method, call.Url, ua)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout float64
+		want    float64
+	}{
+		{"default", 0, ansible.DEFAULT_REQUEST_TIMEOUT},
+		{"configured", 7.5, 7.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ansible.NewConfig()
+			config.RequestTimeout = tt.timeout
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", config)
+			root := renderCollection(t, m)
+			responses := []fakeResponse{{Url: testWidgetLink, Body: map[string]any{"name": "w", "displayName": "My widget"}}}
+
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+			if got.Failed || len(got.Calls) == 0 {
+				t.Fatalf("module failed: %v", got.Result)
+			}
+			for _, call := range got.Calls {
+				if call.Timeout != tt.want {
+					t.Errorf("%s %s timeout = %v, want %v", call.Method, call.Url, call.Timeout, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNoWait(t *testing.T) {
+	widgetYAML := strings.Replace(testResourceYAML, "parameters:", testAsyncYAML+"parameters:", 1)
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	args["wait"] = false
+	responses := []fakeResponse{
+		{Url: testWidgetLink, Status: 404},
+		{Method: "POST", Body: map[string]any{"name": "operations/create", "done": false}},
+	}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	if got.Result["changed"] != true || got.Result["operation"] != "operations/create" {
+		t.Errorf("result = %v, want changed with the operation name", got.Result)
+	}
+	if calls := got.methods(); len(calls) != 2 || !strings.HasPrefix(calls[1], "POST ") {
+		t.Errorf("calls = %v, want no polling after the create", calls)
+	}
+}
+
+func TestResultFromRead(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	args["size"] = "L"
+	responses := []fakeResponse{
+		{Url: testWidgetLink, Status: 404, Times: once()},
+		// the create response isn't the resource, the final read is
+		{Method: "POST", Body: map[string]any{"name": "operations/create"}},
+		{Url: testWidgetLink, Body: map[string]any{"name": "w", "displayName": "My widget", "size": "XL", "createTime": "2025-01-01T00:00:00Z", "etag": "abc"}},
+	}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	want := map[string]any{"changed": true, "display_name": "My widget", "size": "XL", "create_time": "2025-01-01T00:00:00Z"}
+	if mustJSON(t, got.Result) != mustJSON(t, want) {
+		t.Errorf("result = %v, want %v", got.Result, want)
+	}
+}
+
+func TestSnakeCaseResult(t *testing.T) {
+	widgetYAML := testResourceYAML + `  - name: lastError
+    type: NestedObject
+    description: The last error.
+    output: true
+    properties:
+      - name: errorCode
+        type: Integer
+        description: The error code.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	existing := map[string]any{"name": "w", "displayName": "My widget", "createTime": "2025-01-01T00:00:00Z", "lastError": map[string]any{"errorCode": 3}}
+	responses := []fakeResponse{{Url: testWidgetLink, Body: existing}}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	want := map[string]any{"changed": false, "display_name": "My widget", "create_time": "2025-01-01T00:00:00Z", "last_error": map[string]any{"error_code": 3}}
+	if mustJSON(t, got.Result) != mustJSON(t, want) {
+		t.Errorf("result = %v, want %v", got.Result, want)
+	}
+}
+
+func TestRawResponse(t *testing.T) {
+	widgetYAML := "expose_raw_response: true\n" + testResourceYAML + `  - name: adminPassword
+    type: String
+    description: The admin password.
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	existing := map[string]any{"name": "w", "displayName": "My widget", "adminPassword": "hunter2", "undocumented": map[string]any{"a": 1}}
+	responses := []fakeResponse{{Url: testWidgetLink, Body: existing}}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: testWidgetArgs(), Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	want := map[string]any{"name": "w", "displayName": "My widget", "adminPassword": "********", "undocumented": map[string]any{"a": 1}}
+	if mustJSON(t, got.Result["_raw"]) != mustJSON(t, want) {
+		t.Errorf("_raw = %v, want %v", got.Result["_raw"], want)
+	}
+	if _, ok := got.Result["undocumented"]; ok {
+		t.Errorf("the undocumented field is returned outside _raw: %v", got.Result)
+	}
+}
+
+func TestCustomCodeHooks(t *testing.T) {
+	customCode := `custom_code:
+  encoder: |
+    request["size"] = request.get("size") or "M"
+  decoder: |
+    response["displayName"] = response["displayName"].lower()
+`
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": customCode + testResourceYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	args["display_name"] = "my widget"
+	created := map[string]any{"name": "w", "displayName": "MY WIDGET", "size": "M"}
+	responses := []fakeResponse{
+		{Url: testWidgetLink, Status: 404, Times: once()},
+		{Method: "POST", Body: created},
+		{Url: testWidgetLink, Body: created},
+	}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if got.Failed {
+		t.Fatalf("module failed: %v", got.Result)
+	}
+	if !slices.ContainsFunc(got.methods(), func(call string) bool { return strings.HasPrefix(call, "POST ") }) {
+		t.Fatalf("calls = %v, want a create", got.methods())
+	}
+	for _, call := range got.Calls {
+		if call.Method == "POST" && call.Body["size"] != "M" {
+			t.Errorf("POST size = %v, want the encoder default", call.Body["size"])
+		}
+	}
+	if got.Result["display_name"] != "my widget" {
+		t.Errorf("result display_name = %v, want it decoded", got.Result["display_name"])
+	}
+}
+
+func TestPatchOnly(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": "patch_only:\n  - size\n" + testResourceYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	existing := map[string]any{"name": "w", "displayName": "My widget", "size": "S"}
+	tests := []struct {
+		name       string
+		args       map[string]any
+		wantFailed bool
+	}{
+		{"patchable", map[string]any{"size": "L"}, false},
+		{"not patchable", map[string]any{"display_name": "Renamed", "size": "L"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := testWidgetArgs()
+			maps.Copy(args, tt.args)
+			responses := []fakeResponse{
+				{Url: testWidgetLink, Body: existing, Times: once()},
+				{Method: "PATCH", Body: map[string]any{}},
+				{Url: testWidgetLink, Body: existing},
+			}
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+			if got.Failed != tt.wantFailed {
+				t.Fatalf("failed = %v, want %v: %v", got.Failed, tt.wantFailed, got.Result)
+			}
+			if tt.wantFailed {
+				if msg, _ := got.Result["msg"].(string); msg != "field displayName cannot be updated in place, only size can" {
+					t.Errorf("msg = %q", msg)
+				}
+				if slices.ContainsFunc(got.methods(), func(call string) bool { return strings.HasPrefix(call, "PATCH ") }) {
+					t.Errorf("updated anyway: %v", got.methods())
+				}
+				return
+			}
+			if want := "PATCH " + testWidgetLink + "?updateMask=size"; !slices.Contains(got.methods(), want) {
+				t.Errorf("calls = %v, want %s", got.methods(), want)
+			}
+		})
+	}
+}
+
+func TestBase64Encoding(t *testing.T) {
+	widgetYAML := strings.Replace(testResourceYAML, "    description: The widget size.\n", "    description: The widget size.\n    encoding: base64\n", 1)
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": widgetYAML}, "Widget", nil)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	args["size"] = "large"
+	created := map[string]any{"name": "w", "displayName": "My widget", "size": "bGFyZ2U="}
+
+	t.Run("encoded on create", func(t *testing.T) {
+		responses := []fakeResponse{
+			{Url: testWidgetLink, Status: 404, Times: once()},
+			{Method: "POST", Body: created},
+			{Url: testWidgetLink, Body: created},
+		}
+		got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+		if got.Failed {
+			t.Fatalf("module failed: %v", got.Result)
+		}
+		if !slices.ContainsFunc(got.methods(), func(call string) bool { return strings.HasPrefix(call, "POST ") }) {
+			t.Fatalf("calls = %v, want a create", got.methods())
+		}
+		for _, call := range got.Calls {
+			if call.Method == "POST" && call.Body["size"] != "bGFyZ2U=" {
+				t.Errorf("POST size = %v, want the base64 encoding of large", call.Body["size"])
+			}
+		}
+		if got.Result["size"] != "large" {
+			t.Errorf("result size = %v, want it decoded", got.Result["size"])
+		}
+	})
+
+	t.Run("decoded on read", func(t *testing.T) {
+		responses := []fakeResponse{{Url: testWidgetLink, Body: created}}
+		got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+		if got.Failed {
+			t.Fatalf("module failed: %v", got.Result)
+		}
+		if got.Result["changed"] != false {
+			t.Errorf("changed = %v, want the decoded value to match", got.Result["changed"])
+		}
+		if got.Result["size"] != "large" {
+			t.Errorf("result size = %v, want it decoded", got.Result["size"])
+		}
+	})
+}
+
+func TestValidateScopes(t *testing.T) {
+	config := ansible.NewConfig()
+	config.ValidateScopes = true
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": strings.ReplaceAll(testResourceYAML, "location", "zone")}, "Widget", config)
+	root := renderCollection(t, m)
+	args := testWidgetArgs()
+	delete(args, "location")
+	args["zone"] = "nowhere-1a"
+	zoneLink := "https://compute.googleapis.com/compute/v1/projects/p/zones/nowhere-1a"
+	widgetLink := "https://widgets.googleapis.com/v1/projects/p/zones/nowhere-1a/widgets/w"
+	responses := []fakeResponse{
+		{Url: zoneLink, Status: 404},
+		{Url: widgetLink, Body: map[string]any{"name": "w", "displayName": "My widget"}},
+	}
+
+	got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses})
+	if msg, _ := got.Result["msg"].(string); !got.Failed || msg != "zone nowhere-1a doesn't exist in project p" {
+		t.Errorf("result = %v, want the missing zone failure", got.Result)
+	}
+	if calls := got.methods(); !slices.Equal(calls, []string{"GET " + zoneLink}) {
+		t.Errorf("calls = %v, want the zone check only", calls)
+	}
+
+	got = runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: responses, CheckMode: true})
+	if got.Failed || slices.Contains(got.methods(), "GET "+zoneLink) {
+		t.Errorf("the zone is checked in check mode: %v %v", got.Result, got.methods())
+	}
+}
+
+func TestOutputPaths(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	tests := []struct {
+		name       string
+		modulePath string
+		testPath   string
+		wantModule string
+		wantTasks  string
+	}{
+		{"default", "", "", "plugins/modules/" + m.Name + ".py", "tests/integration/targets/" + m.Name + "/tasks/main.yml"},
+		{"custom", "modules", "targets", "modules/" + m.Name + ".py", "targets/" + m.Name + "/tasks/main.yml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			td := NewTemplateData(TEST_TEMPLATE_DIR, root, tt.modulePath, tt.testPath, true)
+			if err := td.GenerateCode(m); err != nil {
+				t.Fatal(err)
+			}
+			if err := td.GenerateTests(m); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{tt.wantModule, tt.wantTasks} {
+				if _, err := os.Stat(filepath.Join(root, want)); err != nil {
+					t.Errorf("%s wasn't generated: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteNotFound(t *testing.T) {
+	existing := map[string]any{"name": "w", "displayName": "My widget"}
+	tests := []struct {
+		name         string
+		resourceYAML string
+		responses    []fakeResponse
+		wantFailed   bool
+	}{
+		{"missing", testResourceYAML, []fakeResponse{{Url: testWidgetLink, Status: 404}}, false},
+		{
+			name:         "deleted meanwhile",
+			resourceYAML: testResourceYAML,
+			responses:    []fakeResponse{{Url: testWidgetLink, Body: existing, Times: once()}, {Method: "DELETE", Status: 404}, {Url: testWidgetLink, Status: 404}},
+		},
+		{"missing override", "delete_not_found_is_ok: false\n" + testResourceYAML, []fakeResponse{{Url: testWidgetLink, Status: 404}}, true},
+		{
+			name:         "deleted meanwhile override",
+			resourceYAML: "delete_not_found_is_ok: false\n" + testResourceYAML,
+			responses:    []fakeResponse{{Url: testWidgetLink, Body: existing, Times: once()}, {Method: "DELETE", Status: 404}, {Url: testWidgetLink, Status: 404}},
+			wantFailed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModule(t, testProductYAML, map[string]string{"Widget": tt.resourceYAML}, "Widget", nil)
+			if got := m.DeleteNotFoundIsOk(); got == tt.wantFailed {
+				t.Errorf("DeleteNotFoundIsOk() = %v", got)
+			}
+			root := renderCollection(t, m)
+			args := testWidgetArgs()
+			args["state"] = "absent"
+			got := runModule(t, root, moduleRun{Module: m.Name, Args: args, Responses: tt.responses})
+			if got.Failed != tt.wantFailed {
+				t.Fatalf("failed = %v, want %v: %v", got.Failed, tt.wantFailed, got.Result)
+			}
+			if !tt.wantFailed && got.Result["changed"] != false {
+				t.Errorf("changed = %v, want false", got.Result["changed"])
+			}
+		})
+	}
+}
+
+func TestGenerateUnitTests(t *testing.T) {
+	m := newTestModule(t, testProductYAML, map[string]string{"Widget": testResourceYAML}, "Widget", nil)
+	root := t.TempDir()
+	td := NewTemplateData(TEST_TEMPLATE_DIR, root, "", "", false)
+	if err := td.GenerateUnitTests(m); err != nil {
+		t.Fatal(err)
+	}
+
+	unitTest := filepath.Join(root, "tests", "unit", "plugins", "modules", "test_"+m.Name+".py")
+	content, err := os.ReadFile(unitTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"from ansible_collections.google.cloud.plugins.modules import " + m.Name + " as module_under_test",
+		`REQUIRED_OPTIONS = ["auth_kind","display_name","location","name","project"]`,
+		`"state":["present","absent"]`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("want %s in the unit test:\n%s", want, content)
+		}
+	}
+
+	// an existing unit test is kept unless overwriting
+	if err := os.WriteFile(unitTest, []byte("# edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := td.GenerateUnitTests(m); err != nil {
+		t.Fatal(err)
+	}
+	if kept, _ := os.ReadFile(unitTest); string(kept) != "# edited\n" {
+		t.Errorf("the existing unit test was overwritten:\n%s", kept)
+	}
+}
@@ -0,0 +1,5 @@
+"""Test stub of ansible.errors"""
+
+
+class AnsibleError(Exception):
+    pass
@@ -0,0 +1,66 @@
+"""Test stub of ansible.module_utils.basic, the module arguments are set by the
+harness (ARGS) and exit_json/fail_json raise instead of exiting"""
+
+ARGS = {}
+CHECK_MODE = False
+DIFF = False
+VERBOSITY = 0
+
+
+class ModuleExit(SystemExit):
+    """Raised by exit_json and fail_json, a SystemExit like the real sys.exit
+    so the module's except Exception blocks don't catch it"""
+
+    def __init__(self, failed, result):
+        super(ModuleExit, self).__init__(result.get("msg", ""))
+        self.failed = failed
+        self.result = result
+
+
+def env_fallback(*args, **kwargs):
+    return None
+
+
+def missing_required_lib(library, reason=None, url=None):
+    return "Failed to import the required Python library (%s)" % library
+
+
+class AnsibleModule(object):
+    def __init__(self, argument_spec=None, supports_check_mode=False, mutually_exclusive=None, **kwargs):
+        self.argument_spec = argument_spec or {}
+        self.mutually_exclusive = mutually_exclusive or []
+        self.kwargs = kwargs
+        self.check_mode = CHECK_MODE and supports_check_mode
+        self._diff = DIFF
+        self._verbosity = VERBOSITY
+        self.debugs = []
+        self.logs = []
+        self.warnings = []
+        # like ansible, the mutually exclusive options are checked before the
+        # defaults are set
+        for group in self.mutually_exclusive:
+            given = [name for name in group if ARGS.get(name) is not None]
+            if len(given) > 1:
+                self.fail_json(msg="parameters are mutually exclusive: %s" % "|".join(group))
+        self.params = {}
+        for name, spec in self.argument_spec.items():
+            value = ARGS.get(name)
+            if value is None:
+                value = spec.get("default")
+            self.params[name] = value
+
+    def debug(self, msg):
+        self.debugs.append(msg)
+
+    def log(self, msg, log_args=None):
+        self.logs.append(msg)
+
+    def warn(self, warning):
+        self.warnings.append(warning)
+
+    def exit_json(self, **kwargs):
+        raise ModuleExit(False, kwargs)
+
+    def fail_json(self, msg, **kwargs):
+        kwargs["msg"] = msg
+        raise ModuleExit(True, kwargs)
@@ -0,0 +1,13 @@
+"""Test stub of ansible.module_utils.common.text.converters"""
+
+
+def to_bytes(value, encoding="utf-8"):
+    if isinstance(value, bytes):
+        return value
+    return str(value).encode(encoding)
+
+
+def to_text(value, encoding="utf-8"):
+    if isinstance(value, bytes):
+        return value.decode(encoding)
+    return str(value)
@@ -0,0 +1,3 @@
+"""Test stub of ansible.module_utils.six.moves.urllib.parse"""
+
+from urllib.parse import urlencode  # noqa: F401
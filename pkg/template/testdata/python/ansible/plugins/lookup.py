@@ -0,0 +1,5 @@
+"""Test stub of ansible.plugins.lookup"""
+
+
+class LookupBase(object):
+    pass
@@ -0,0 +1,99 @@
+"""Runs a generated module (or lookup plugin) against a fake API: reads the
+module name, its arguments and the API responses as JSON from stdin and prints
+the module result, the API calls and the debug messages as JSON"""
+
+import importlib
+import json
+import sys
+
+from ansible.module_utils import basic
+from ansible_collections.google.cloud.plugins.module_utils import gcp_utils as gcp
+
+
+class FakeRequest(object):
+    def __init__(self, method):
+        self.method = method.upper()
+
+
+class FakeResponse(object):
+    def __init__(self, method, url, status, body):
+        self.request = FakeRequest(method)
+        self.url = url
+        self.status_code = status
+        self.body = body
+        self.content = b"" if body is None else json.dumps(body).encode()
+        self.text = self.content.decode()
+
+    def json(self):
+        if self.body is None:
+            raise ValueError("no JSON body")
+        return self.body
+
+
+class FakeSession(object):
+    """Answers with the first response matching the method and (a part of)
+    the URL, a response with times is only used that many times"""
+
+    def __init__(self, responses):
+        self.responses = responses
+        self.calls = []
+
+    def request(self, method, url, json=None, headers=None, timeout=None):
+        self.calls.append({"method": method.upper(), "url": url, "body": json, "headers": headers or {}, "timeout": timeout})
+        for response in self.responses:
+            if response.get("times") == 0:
+                continue
+            if response.get("method", "GET") != method.upper() or response.get("url", "") not in url:
+                continue
+            if "times" in response:
+                response["times"] -= 1
+            return FakeResponse(method, url, response.get("status", 200), response.get("body"))
+        return FakeResponse(method, url, 500, {"error": {"message": "unexpected call"}})
+
+
+def main():
+    spec = json.load(sys.stdin)
+    basic.ARGS = spec.get("args", {})
+    basic.CHECK_MODE = spec.get("check_mode", False)
+    basic.DIFF = spec.get("diff", False)
+    basic.VERBOSITY = spec.get("verbosity", 0)
+    session = FakeSession(spec.get("responses", []))
+    gcp.HAS_GOOGLE_AUTH = True
+    gcp.Resource.session = lambda self: session
+    gcp.time.sleep = lambda seconds: None
+
+    captured = {}
+    original_init = gcp.Module.__init__
+
+    def init(self, *args, **kwargs):
+        captured["module"] = self
+        original_init(self, *args, **kwargs)
+
+    gcp.Module.__init__ = init
+
+    if spec.get("lookup"):
+        from ansible.errors import AnsibleError
+
+        lookup = importlib.import_module("ansible_collections.google.cloud.plugins.lookup." + spec["lookup"])
+        try:
+            output = {"failed": False, "result": {"raw": lookup.LookupModule().run(spec["terms"], **spec["args"])}}
+        except AnsibleError as e:
+            output = {"failed": True, "result": {"msg": str(e)}}
+        output["calls"] = session.calls
+        json.dump(output, sys.stdout)
+        return
+
+    module = importlib.import_module("ansible_collections.google.cloud.plugins.modules." + spec["module"])
+    try:
+        module.main()
+        output = {"failed": True, "result": {"msg": "the module didn't exit"}}
+    except basic.ModuleExit as e:
+        output = {"failed": e.failed, "result": e.result}
+    output["calls"] = session.calls
+    output["debug"] = getattr(captured.get("module"), "debugs", [])
+    output["log"] = getattr(captured.get("module"), "logs", [])
+    json.dump(output, sys.stdout)
+
+
+if __name__ == "__main__":
+    main()
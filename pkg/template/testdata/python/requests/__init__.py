@@ -0,0 +1,3 @@
+"""Test stub of requests, only its exceptions are used by gcp_utils"""
+
+from requests import exceptions  # noqa: F401
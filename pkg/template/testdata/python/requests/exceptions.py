@@ -0,0 +1,5 @@
+"""Test stub of requests.exceptions"""
+
+
+class RequestException(IOError):
+    pass